@@ -0,0 +1,23 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/go-obvious/gateway/internal"
+)
+
+func ListenAndServeALB(addr string, h http.Handler) error {
+	return New(h,
+		WithRequestConverter(internal.ConvertALBTargetGroupRequest),
+		WithResponseConverter(internal.ConvertResponseALB),
+	).ListenAndServe(addr)
+}
+
+// ALBEvent returns the ALB target group event that produced the request, if it
+// was served from one.
+func ALBEvent(ctx context.Context) (events.ALBTargetGroupRequest, bool) {
+	return internal.RequestContext[events.ALBTargetGroupRequest](ctx)
+}
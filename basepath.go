@@ -0,0 +1,35 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/go-obvious/gateway/internal"
+)
+
+// BasePath holds the fields of an event a BasePathPolicy decides from.
+type BasePath = internal.BasePath
+
+// BasePathPolicy returns the prefix of the request path to strip before routing.
+type BasePathPolicy = internal.BasePathPolicy
+
+// StripStage strips the stage API Gateway puts in front of the path of non-$default stages.
+func StripStage() BasePathPolicy {
+	return internal.StripStage()
+}
+
+// StripPrefix strips a fixed prefix, such as the base path of a custom domain mapping.
+func StripPrefix(prefix string) BasePathPolicy {
+	return internal.StripPrefix(prefix)
+}
+
+// DetectBasePath strips whatever precedes the part of the path matched by the
+// API Gateway route, which covers stages and custom domain mappings alike.
+func DetectBasePath() BasePathPolicy {
+	return internal.DetectBasePath()
+}
+
+// BasePathFromContext returns the prefix stripped from the request path, so
+// handlers can build absolute URLs.
+func BasePathFromContext(ctx context.Context) string {
+	return internal.BasePathFromContext(ctx)
+}
@@ -0,0 +1,17 @@
+package gateway
+
+import "github.com/go-obvious/gateway/internal"
+
+// BinaryHeader lets a handler decide whether its response body is sent base64
+// encoded with "true" or "false". The header is removed from the response.
+const BinaryHeader = internal.BinaryHeader
+
+// BinaryPolicy reports whether a response with the given headers has a binary body.
+type BinaryPolicy = internal.BinaryPolicy
+
+// BinaryMediaTypes returns a BinaryPolicy that, like the binaryMediaTypes of a
+// REST API, treats bodies of the binary types as binary and those of the text
+// types as text. Patterns may end in a wildcard, such as "image/*" or "*/*".
+func BinaryMediaTypes(binary, text []string) BinaryPolicy {
+	return internal.BinaryMediaTypes(binary, text)
+}
@@ -0,0 +1,10 @@
+package gateway
+
+import "github.com/go-obvious/gateway/internal"
+
+// DefaultCompressionMinSize is the smallest body compressed when Compression has no MinSize.
+const DefaultCompressionMinSize = internal.DefaultCompressionMinSize
+
+// Compression configures the compression of response bodies negotiated from
+// the Accept-Encoding header of the request.
+type Compression = internal.Compression
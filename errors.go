@@ -0,0 +1,31 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/go-obvious/gateway/internal"
+)
+
+// Errors returned for each stage of an invocation that can fail. The cause is
+// wrapped as well, so errors.Is and errors.As see both.
+var (
+	ErrUnsupportedEvent   = internal.ErrUnsupportedEvent
+	ErrUnmarshal          = internal.ErrUnmarshal
+	ErrRequestConversion  = internal.ErrRequestConversion
+	ErrResponseConversion = internal.ErrResponseConversion
+	ErrHandlerTimeout     = internal.ErrHandlerTimeout
+	ErrResponseTooLarge   = internal.ErrResponseTooLarge
+	ErrUnfoldableHeader   = internal.ErrUnfoldableHeader
+)
+
+// PanicError is the error passed to the ErrorHandler when the handler panics.
+// It unwraps to the panic value if that is an error, such as http.ErrAbortHandler.
+type PanicError = internal.PanicError
+
+// DefaultErrorHandler logs err with the Lambda request ID and answers malformed
+// events with 400 Bad Request, handlers running into the deadline with 504 Gateway
+// Timeout, oversized responses with 502 Bad Gateway and any other failure,
+// including a handler panic, with 500 Internal Server Error.
+func DefaultErrorHandler(ctx context.Context, err error) (ResponseData, error) {
+	return internal.DefaultErrorHandler(ctx, err)
+}
@@ -0,0 +1,101 @@
+package gateway_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/go-obvious/gateway"
+	"github.com/go-obvious/gateway/internal"
+)
+
+func TestV1Event(t *testing.T) {
+	e := events.APIGatewayProxyRequest{
+		HTTPMethod: "GET",
+		Path:       "/test",
+		RequestContext: events.APIGatewayProxyRequestContext{
+			Stage:      "prod",
+			APIID:      "abc123",
+			Authorizer: map[string]interface{}{"claims": map[string]interface{}{"sub": "user-1"}},
+		},
+	}
+
+	req, err := internal.ConvertAPIGatewayProxyRequest(context.Background(), e)
+	if err != nil {
+		t.Fatalf("ConvertAPIGatewayProxyRequest failed: %v", err)
+	}
+
+	got, ok := gateway.V1Event(req.Context())
+	if !ok {
+		t.Fatalf("expected the V1 event in the request context")
+	}
+
+	if got.RequestContext.Stage != "prod" || got.RequestContext.APIID != "abc123" {
+		t.Errorf("expected stage prod and API ID abc123, got %s and %s", got.RequestContext.Stage, got.RequestContext.APIID)
+	}
+
+	claims, _ := got.RequestContext.Authorizer["claims"].(map[string]interface{})
+	if claims["sub"] != "user-1" {
+		t.Errorf("expected the authorizer claims, got %v", got.RequestContext.Authorizer)
+	}
+
+	// The event of another format is not reported
+	if _, ok := gateway.V2Event(req.Context()); ok {
+		t.Errorf("expected no V2 event in a V1 request context")
+	}
+}
+
+func TestV2Event(t *testing.T) {
+	e := events.APIGatewayV2HTTPRequest{
+		RawPath: "/test",
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			Stage: "$default",
+			APIID: "abc123",
+			HTTP:  events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET"},
+			Authorizer: &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+				JWT: &events.APIGatewayV2HTTPRequestContextAuthorizerJWTDescription{Claims: map[string]string{"sub": "user-1"}},
+			},
+		},
+	}
+
+	req, err := internal.ConvertAPIGatewayV2HTTPRequest(context.Background(), e)
+	if err != nil {
+		t.Fatalf("ConvertAPIGatewayV2HTTPRequest failed: %v", err)
+	}
+
+	got, ok := gateway.V2Event(req.Context())
+	if !ok {
+		t.Fatalf("expected the V2 event in the request context")
+	}
+
+	if got.RequestContext.Authorizer.JWT.Claims["sub"] != "user-1" {
+		t.Errorf("expected the JWT claims, got %v", got.RequestContext.Authorizer.JWT.Claims)
+	}
+}
+
+func TestALBEvent(t *testing.T) {
+	e := events.ALBTargetGroupRequest{
+		HTTPMethod:     "GET",
+		Path:           "/test",
+		RequestContext: events.ALBTargetGroupRequestContext{ELB: events.ELBContext{TargetGroupArn: "arn:test"}},
+	}
+
+	req, err := internal.ConvertALBTargetGroupRequest(context.Background(), e)
+	if err != nil {
+		t.Fatalf("ConvertALBTargetGroupRequest failed: %v", err)
+	}
+
+	got, ok := gateway.ALBEvent(req.Context())
+	if !ok {
+		t.Fatalf("expected the ALB event in the request context")
+	}
+
+	if got.RequestContext.ELB.TargetGroupArn != "arn:test" {
+		t.Errorf("expected target group arn:test, got %s", got.RequestContext.ELB.TargetGroupArn)
+	}
+
+	if _, ok := gateway.V1Event(context.Background()); ok {
+		t.Errorf("expected no event in an empty context")
+	}
+}
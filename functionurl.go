@@ -0,0 +1,40 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/go-obvious/gateway/internal"
+)
+
+func ListenAndServeFunctionURL(addr string, h http.Handler) error {
+	return New(h,
+		WithRequestConverter(internal.ConvertLambdaFunctionURLRequest),
+		WithResponseConverter(internal.ConvertResponseFunctionURL),
+	).ListenAndServe(addr)
+}
+
+// FunctionURLEvent returns the Function URL event that produced the request, if
+// it was served from one.
+func FunctionURLEvent(ctx context.Context) (events.LambdaFunctionURLRequest, bool) {
+	return internal.RequestContext[events.LambdaFunctionURLRequest](ctx)
+}
+
+// FunctionURLRequestContext returns the Function URL request context of the
+// event that produced the request, if it was served by ListenAndServeFunctionURL.
+func FunctionURLRequestContext(ctx context.Context) (events.LambdaFunctionURLRequestContext, bool) {
+	e, ok := FunctionURLEvent(ctx)
+	return e.RequestContext, ok
+}
+
+// ListenAndServeFunctionURLStream serves h behind a Function URL configured with
+// InvokeMode RESPONSE_STREAM. Writes and http.Flusher flushes reach the client as they happen.
+func ListenAndServeFunctionURLStream(addr string, h http.Handler) error {
+	return internal.ListenAndServeStream[events.LambdaFunctionURLRequest](
+		addr,
+		h,
+		internal.ConvertLambdaFunctionURLRequest,
+	)
+}
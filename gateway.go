@@ -0,0 +1,48 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-obvious/gateway/internal"
+)
+
+// ListenAndServe serves h behind API Gateway V1, API Gateway V2, an ALB or a
+// Function URL. The format of every event is detected when it arrives and the
+// response is returned in the matching format.
+func ListenAndServe(addr string, h http.Handler) error {
+	return New(h).ListenAndServe(addr)
+}
+
+// Gateway serves an http.Handler as a Lambda function. It implements lambda.Handler.
+type Gateway struct {
+	handler http.Handler
+	gw      *internal.AutoGateway
+}
+
+// New returns a Gateway for h, or http.DefaultServeMux when h is nil. Without
+// options the format of every event is detected when it arrives.
+func New(h http.Handler, opts ...Option) *Gateway {
+	if h == nil {
+		h = http.DefaultServeMux
+	}
+
+	options := make([]internal.Option, len(opts))
+	for i, opt := range opts {
+		options[i] = internal.Option(opt)
+	}
+
+	return &Gateway{handler: h, gw: internal.NewAutoGateway(h, options...)}
+}
+
+// Invoke serves a single Lambda invocation and returns the response payload.
+func (g *Gateway) Invoke(ctx context.Context, payload []byte) ([]byte, error) {
+	return g.gw.Invoke(ctx, payload)
+}
+
+// ListenAndServe starts the Lambda handler. Outside of Lambda it serves the
+// handler on addr with net/http instead, or through the local emulator when
+// GATEWAY_EMULATOR is set.
+func (g *Gateway) ListenAndServe(addr string) error {
+	return internal.Serve(addr, g.handler, g.gw, g.gw.Format())
+}
@@ -0,0 +1,83 @@
+package gateway_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/go-obvious/gateway"
+	"github.com/go-obvious/gateway/gatewaytest"
+)
+
+func TestNew(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello, World!"))
+	})
+
+	out, err := gateway.New(h).Invoke(context.Background(), gatewaytest.NewV2Event(httptest.NewRequest("GET", "/", nil)))
+	if err != nil {
+		t.Fatalf("Invoke failed: %v", err)
+	}
+
+	resp, err := gatewaytest.DecodeV2Response(out)
+	if err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+}
+
+func TestNew_Options(t *testing.T) {
+	errConvert := errors.New("unsupported path")
+
+	gw := gateway.New(http.NotFoundHandler(),
+		gateway.WithRequestConverter(func(ctx context.Context, e events.APIGatewayProxyRequest) (*http.Request, error) {
+			if e.Path != "/" {
+				return nil, errConvert
+			}
+			return http.NewRequestWithContext(ctx, e.HTTPMethod, e.Path, nil)
+		}),
+		gateway.WithResponseConverter(func(data gateway.ResponseData) (events.APIGatewayProxyResponse, error) {
+			return events.APIGatewayProxyResponse{StatusCode: data.StatusCode, Body: "custom"}, nil
+		}),
+		gateway.WithErrorHandler(func(ctx context.Context, err error) (gateway.ResponseData, error) {
+			if !errors.Is(err, errConvert) {
+				t.Errorf("expected the conversion error, got %v", err)
+			}
+			return gateway.ResponseData{StatusCode: http.StatusBadRequest}, nil
+		}),
+	)
+
+	tests := []struct {
+		name     string
+		path     string
+		expected int
+	}{
+		{"converted", "/", http.StatusNotFound},
+		{"conversion error", "/other", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := gatewaytest.Invoke(gw, gatewaytest.NewV1Event(httptest.NewRequest("GET", tt.path, nil)))
+			if err != nil {
+				t.Fatalf("Invoke failed: %v", err)
+			}
+
+			resp, err := gatewaytest.DecodeV1Response(out)
+			if err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if resp.StatusCode != tt.expected {
+				t.Errorf("expected status code %d, got %d", tt.expected, resp.StatusCode)
+			}
+		})
+	}
+}
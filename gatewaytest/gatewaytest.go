@@ -0,0 +1,129 @@
+// Package gatewaytest provides utilities for testing http.Handlers served
+// through the gateway package without deploying them to Lambda.
+//
+// Events are built from ordinary *http.Request values, such as those returned
+// by httptest.NewRequest, and response events decode back into *http.Response
+// values, so assertions look like any other net/http test:
+//
+//	r := httptest.NewRequest("GET", "/users/42", nil)
+//	payload := gatewaytest.NewV2Event(r)
+//	out, err := gatewaytest.Invoke(gatewaytest.Handler(mux), payload)
+//	resp, err := gatewaytest.DecodeV2Response(out)
+package gatewaytest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+
+	"github.com/go-obvious/gateway/internal"
+)
+
+// Invoker is implemented by gateways that handle raw Lambda payloads.
+type Invoker interface {
+	Invoke(ctx context.Context, payload []byte) ([]byte, error)
+}
+
+// Handler returns an Invoker that serves h the way gateway.ListenAndServe does,
+// detecting the format of every event.
+func Handler(h http.Handler) Invoker {
+	return internal.NewAutoGateway(h)
+}
+
+// ===========================
+// Event Builders
+// ===========================
+
+// NewV1Event returns the API Gateway V1 (REST API) event JSON for r.
+// It panics if the body of r cannot be read, like httptest.NewRequest.
+func NewV1Event(r *http.Request) []byte {
+	return newEvent(internal.FormatAPIGatewayV1, r)
+}
+
+// NewV2Event returns the API Gateway V2 (HTTP API) event JSON for r.
+// It panics if the body of r cannot be read, like httptest.NewRequest.
+func NewV2Event(r *http.Request) []byte {
+	return newEvent(internal.FormatAPIGatewayV2, r)
+}
+
+// NewALBEvent returns the ALB target group event JSON for r.
+// It panics if the body of r cannot be read, like httptest.NewRequest.
+func NewALBEvent(r *http.Request) []byte {
+	return newEvent(internal.FormatALB, r)
+}
+
+// NewFunctionURLEvent returns the Lambda Function URL event JSON for r.
+// It panics if the body of r cannot be read, like httptest.NewRequest.
+func NewFunctionURLEvent(r *http.Request) []byte {
+	return newEvent(internal.FormatFunctionURL, r)
+}
+
+func newEvent(format internal.EventFormat, r *http.Request) []byte {
+	payload, err := internal.BuildEvent(format, r)
+	if err != nil {
+		panic("gatewaytest: " + err.Error())
+	}
+	return payload
+}
+
+// ===========================
+// Invocation
+// ===========================
+
+// Invoke passes payload to gw with a fake lambdacontext, as the Lambda runtime would.
+// The Lambda request ID matches the request ID of the event when it has one.
+func Invoke(gw Invoker, payload []byte) ([]byte, error) {
+	return InvokeWithContext(context.Background(), gw, payload)
+}
+
+// InvokeWithContext is like Invoke but derives the invocation context from ctx.
+func InvokeWithContext(ctx context.Context, gw Invoker, payload []byte) ([]byte, error) {
+	if _, ok := lambdacontext.FromContext(ctx); !ok {
+		ctx = lambdacontext.NewContext(ctx, &lambdacontext.LambdaContext{
+			AwsRequestID:       eventRequestID(payload),
+			InvokedFunctionArn: "arn:aws:lambda:local:000000000000:function:gatewaytest",
+		})
+	}
+	return gw.Invoke(ctx, payload)
+}
+
+// eventRequestID returns the request ID of an event, or a fixed ID for events without one.
+func eventRequestID(payload []byte) string {
+	var probe struct {
+		RequestContext struct {
+			RequestID string `json:"requestId"`
+		} `json:"requestContext"`
+	}
+	if err := json.Unmarshal(payload, &probe); err != nil || probe.RequestContext.RequestID == "" {
+		return "00000000-0000-0000-0000-000000000000"
+	}
+	return probe.RequestContext.RequestID
+}
+
+// ===========================
+// Response Decoders
+// ===========================
+
+// DecodeV1Response turns an API Gateway V1 response event into an *http.Response.
+// Headers are merged the way API Gateway merges headers and multiValueHeaders.
+func DecodeV1Response(payload []byte) (*http.Response, error) {
+	return internal.DecodeResponse(internal.FormatAPIGatewayV1, payload)
+}
+
+// DecodeV2Response turns an API Gateway V2 response event into an *http.Response.
+// Like HTTP APIs it ignores multiValueHeaders and turns cookies into Set-Cookie headers.
+func DecodeV2Response(payload []byte) (*http.Response, error) {
+	return internal.DecodeResponse(internal.FormatAPIGatewayV2, payload)
+}
+
+// DecodeALBResponse turns an ALB target group response event into an *http.Response.
+func DecodeALBResponse(payload []byte) (*http.Response, error) {
+	return internal.DecodeResponse(internal.FormatALB, payload)
+}
+
+// DecodeFunctionURLResponse turns a Lambda Function URL response event into an *http.Response.
+func DecodeFunctionURLResponse(payload []byte) (*http.Response, error) {
+	return internal.DecodeResponse(internal.FormatFunctionURL, payload)
+}
@@ -0,0 +1,77 @@
+package gatewaytest_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+
+	"github.com/go-obvious/gateway/gatewaytest"
+)
+
+func TestRoundTrip(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /users", func(w http.ResponseWriter, r *http.Request) {
+		lc, ok := lambdacontext.FromContext(r.Context())
+		if !ok {
+			t.Errorf("expected a fake Lambda context")
+		} else if id := r.Header.Get("X-Request-Id"); id != "" && id != lc.AwsRequestID {
+			t.Errorf("expected Lambda request ID %s to match the event request ID %s", lc.AwsRequestID, id)
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("Set-Cookie", "session=abc")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	})
+
+	tests := []struct {
+		name   string
+		event  func(*http.Request) []byte
+		decode func([]byte) (*http.Response, error)
+	}{
+		{"api gateway v1", gatewaytest.NewV1Event, gatewaytest.DecodeV1Response},
+		{"api gateway v2", gatewaytest.NewV2Event, gatewaytest.DecodeV2Response},
+		{"alb", gatewaytest.NewALBEvent, gatewaytest.DecodeALBResponse},
+		{"function url", gatewaytest.NewFunctionURLEvent, gatewaytest.DecodeFunctionURLResponse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/users", strings.NewReader(`{"name":"gopher"}`))
+			r.Header.Set("Content-Type", "application/json")
+
+			out, err := gatewaytest.Invoke(gatewaytest.Handler(mux), tt.event(r))
+			if err != nil {
+				t.Fatalf("Invoke failed: %v", err)
+			}
+
+			resp, err := tt.decode(out)
+			if err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusCreated {
+				t.Errorf("expected status code %d, got %d", http.StatusCreated, resp.StatusCode)
+			}
+
+			if resp.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("expected Content-Type application/json, got %q", resp.Header.Get("Content-Type"))
+			}
+
+			if resp.Header.Get("Set-Cookie") != "session=abc" {
+				t.Errorf("expected Set-Cookie session=abc, got %q", resp.Header.Get("Set-Cookie"))
+			}
+
+			body, _ := io.ReadAll(resp.Body)
+			if string(body) != `{"name":"gopher"}` {
+				t.Errorf("expected body %q, got %q", `{"name":"gopher"}`, string(body))
+			}
+		})
+	}
+}
@@ -3,7 +3,7 @@ module github.com/go-obvious/gateway
 go 1.23
 
 require (
-	github.com/aws/aws-lambda-go v1.17.0
+	github.com/aws/aws-lambda-go v1.47.0
 	github.com/pkg/errors v0.9.1
 )
 
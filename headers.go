@@ -0,0 +1,20 @@
+package gateway
+
+import "github.com/go-obvious/gateway/internal"
+
+// ContextField names a request context field that can be copied into a request header.
+type ContextField = internal.ContextField
+
+// Request context fields for HeaderPolicy. Fields an event format does not carry are never set.
+const (
+	FieldRequestID  = internal.FieldRequestID
+	FieldStage      = internal.FieldStage
+	FieldAPIID      = internal.FieldAPIID
+	FieldDomainName = internal.FieldDomainName
+	FieldRouteKey   = internal.FieldRouteKey
+	FieldAccountID  = internal.FieldAccountID
+)
+
+// HeaderPolicy decides which request context fields are copied into request
+// headers. An empty Headers map turns injection off.
+type HeaderPolicy = internal.HeaderPolicy
@@ -0,0 +1,16 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/go-obvious/gateway/internal"
+)
+
+// Identity describes the caller of a request the same way for every event format.
+type Identity = internal.Identity
+
+// IdentityFromContext returns the caller of the request, filled in from the
+// event that produced it whatever its format.
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	return internal.IdentityFromContext(ctx)
+}
@@ -0,0 +1,193 @@
+package internal
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/pkg/errors"
+)
+
+// ===========================
+// ALB Request Converter
+// ===========================
+
+// ConvertALBTargetGroupRequest converts ALBTargetGroupRequest to *http.Request
+func ConvertALBTargetGroupRequest(ctx context.Context, e events.ALBTargetGroupRequest) (*http.Request, error) {
+	// Parse the path
+	u, err := url.Parse(e.Path)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing path")
+	}
+
+	// Strip the base path chosen by the gateway options
+	ctx = stripBasePath(ctx, u, BasePath{Path: e.Path})
+
+	// ALB forwards query parameters exactly as the client sent them,
+	// so they are joined back together without being re-escaped.
+	u.RawQuery = albRawQuery(e)
+
+	// Decode the body if it's base64 encoded
+	body := e.Body
+	if e.IsBase64Encoded {
+		b, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return nil, errors.Wrap(err, "decoding base64 body")
+		}
+		body = string(b)
+	}
+
+	// Create a new HTTP request
+	req, err := http.NewRequest(e.HTTPMethod, u.String(), strings.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "creating request")
+	}
+
+	// Manually set RequestURI
+	req.RequestURI = u.RequestURI()
+
+	// Set headers
+	folding := OptionsFromContext(ctx).HeaderFolding
+	for k, values := range e.Headers {
+		req.Header.Del(k)
+		for _, v := range folding.Split(k, values) {
+			req.Header.Add(k, v)
+		}
+	}
+	for k, values := range e.MultiValueHeaders {
+		for _, v := range values {
+			req.Header.Add(k, v)
+		}
+	}
+
+	// Set RemoteAddr from the client entry ALB appends to X-Forwarded-For
+	if ip := albClientIP(req.Header); ip != "" {
+		req.RemoteAddr = ip
+	}
+
+	// Set Content-Length if not already set
+	if req.Header.Get("Content-Length") == "" && body != "" {
+		req.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	}
+
+	// Add custom context values
+	req = req.WithContext(NewIdentityContext(NewContext(ctx, e), identityALB(req)))
+
+	// X-Ray support
+	if traceID := ctx.Value("x-amzn-trace-id"); traceID != nil {
+		req.Header.Set("X-Amzn-Trace-Id", fmt.Sprintf("%v", traceID))
+	}
+
+	// Set Host
+	req.URL.Host = req.Header.Get("Host")
+	req.Host = req.URL.Host
+
+	// The listener protocol is only known from X-Forwarded-Proto
+	setConnection(req, "http", "", "")
+
+	return req, nil
+}
+
+// albClientIP returns the last entry of X-Forwarded-For, the one ALB appends.
+// Any entries before it were sent by the client and cannot be trusted.
+func albClientIP(h http.Header) string {
+	values := h.Values("X-Forwarded-For")
+	if len(values) == 0 {
+		return ""
+	}
+	entries := strings.Split(values[len(values)-1], ",")
+	return strings.TrimSpace(entries[len(entries)-1])
+}
+
+// albRawQuery rebuilds the raw query string from the ALB event. Keys are
+// sorted so the result is stable, since the event carries them in a map.
+func albRawQuery(e events.ALBTargetGroupRequest) string {
+	params := e.MultiValueQueryStringParameters
+	if len(params) == 0 {
+		params = make(map[string][]string, len(e.QueryStringParameters))
+		for k, v := range e.QueryStringParameters {
+			params[k] = []string{v}
+		}
+	}
+
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		for _, v := range params[k] {
+			parts = append(parts, k+"="+v)
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// isALBMultiValue reports whether the target group that sent the event has
+// multi-value headers enabled. ALB only fills the multiValue* fields in that mode.
+func isALBMultiValue(e events.ALBTargetGroupRequest) bool {
+	return e.MultiValueHeaders != nil || e.MultiValueQueryStringParameters != nil
+}
+
+// ===========================
+// ALB Response Converter
+// ===========================
+
+// ConvertResponseALB converts ResponseData to ALBTargetGroupResponse. The
+// response uses multi-value headers when the originating request did, since
+// ALB ignores the other header field in either mode.
+func ConvertResponseALB(data ResponseData) (events.ALBTargetGroupResponse, error) {
+	data, isBin := binaryBody(data)
+
+	out := events.ALBTargetGroupResponse{
+		StatusCode:        data.StatusCode,
+		StatusDescription: fmt.Sprintf("%d %s", data.StatusCode, http.StatusText(data.StatusCode)),
+	}
+
+	multiValue := false
+	if data.Request != nil {
+		if e, ok := RequestContext[events.ALBTargetGroupRequest](data.Request.Context()); ok {
+			multiValue = isALBMultiValue(e)
+		}
+	}
+
+	if multiValue {
+		out.MultiValueHeaders = make(map[string][]string, len(data.Headers))
+		for k, v := range data.Headers {
+			if len(v) > 0 {
+				out.MultiValueHeaders[k] = v
+			}
+		}
+	} else {
+		out.Headers = make(map[string]string, len(data.Headers))
+		for k, v := range data.Headers {
+			if len(v) == 0 {
+				continue
+			}
+			// Only one Set-Cookie can be sent without multi-value headers
+			if http.CanonicalHeaderKey(k) == "Set-Cookie" {
+				out.Headers[k] = v[0]
+			} else {
+				out.Headers[k] = strings.Join(v, ", ")
+			}
+		}
+	}
+
+	out.IsBase64Encoded = isBin
+
+	if isBin {
+		out.Body = base64.StdEncoding.EncodeToString(data.Body)
+	} else {
+		out.Body = string(data.Body)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,234 @@
+package internal
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestConvertALBTargetGroupRequest(t *testing.T) {
+	event := events.ALBTargetGroupRequest{
+		HTTPMethod: "POST",
+		Path:       "/test",
+		QueryStringParameters: map[string]string{
+			"b": "2",
+			"a": "hello%20world",
+		},
+		Headers: map[string]string{
+			"content-type":    "application/json",
+			"host":            "example.com",
+			"x-forwarded-for": "10.0.0.1, 203.0.113.1",
+		},
+		Body:            base64.StdEncoding.EncodeToString([]byte(`{"key":"value"}`)),
+		IsBase64Encoded: true,
+	}
+
+	req, err := ConvertALBTargetGroupRequest(context.Background(), event)
+	if err != nil {
+		t.Fatalf("ConvertALBTargetGroupRequest failed: %v", err)
+	}
+
+	if req.Method != "POST" {
+		t.Errorf("expected method POST, got %s", req.Method)
+	}
+
+	if req.URL.Path != "/test" {
+		t.Errorf("expected path /test, got %s", req.URL.Path)
+	}
+
+	expectedQuery := "a=hello%20world&b=2"
+	if req.URL.RawQuery != expectedQuery {
+		t.Errorf("expected query %s, got %s", expectedQuery, req.URL.RawQuery)
+	}
+
+	if req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("expected Content-Type application/json, got %s", req.Header.Get("Content-Type"))
+	}
+
+	if req.Host != "example.com" {
+		t.Errorf("expected host example.com, got %s", req.Host)
+	}
+
+	if req.RemoteAddr != "203.0.113.1" {
+		t.Errorf("expected RemoteAddr 203.0.113.1, got %s", req.RemoteAddr)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatalf("failed to read request body: %v", err)
+	}
+
+	expectedBody := `{"key":"value"}`
+	if string(body) != expectedBody {
+		t.Errorf("expected body %q, got %q", expectedBody, string(body))
+	}
+
+	if _, ok := RequestContext[events.ALBTargetGroupRequest](req.Context()); !ok {
+		t.Errorf("expected ALB event in request context")
+	}
+}
+
+func TestConvertALBTargetGroupRequest_MultiValue(t *testing.T) {
+	event := events.ALBTargetGroupRequest{
+		HTTPMethod: "GET",
+		Path:       "/test",
+		MultiValueQueryStringParameters: map[string][]string{
+			"param": {"value1", "value2"},
+		},
+		MultiValueHeaders: map[string][]string{
+			"x-custom-header": {"value1", "value2"},
+		},
+	}
+
+	req, err := ConvertALBTargetGroupRequest(context.Background(), event)
+	if err != nil {
+		t.Fatalf("ConvertALBTargetGroupRequest failed: %v", err)
+	}
+
+	expectedQuery := "param=value1&param=value2"
+	if req.URL.RawQuery != expectedQuery {
+		t.Errorf("expected query %s, got %s", expectedQuery, req.URL.RawQuery)
+	}
+
+	if !equalStringSlices(req.Header["X-Custom-Header"], []string{"value1", "value2"}) {
+		t.Errorf("expected X-Custom-Header to have values [value1, value2], got %v", req.Header["X-Custom-Header"])
+	}
+}
+
+func TestConvertALBTargetGroupRequest_ForwardedFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    events.ALBTargetGroupRequest
+		expected string
+	}{
+		{
+			name:     "client supplied entry",
+			event:    events.ALBTargetGroupRequest{Headers: map[string]string{"x-forwarded-for": "1.2.3.4, 9.9.9.9"}},
+			expected: "9.9.9.9",
+		},
+		{
+			name:     "single entry",
+			event:    events.ALBTargetGroupRequest{Headers: map[string]string{"x-forwarded-for": "9.9.9.9"}},
+			expected: "9.9.9.9",
+		},
+		{
+			name:     "multi-value header",
+			event:    events.ALBTargetGroupRequest{MultiValueHeaders: map[string][]string{"x-forwarded-for": {"1.2.3.4", "5.6.7.8, 9.9.9.9"}}},
+			expected: "9.9.9.9",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.event.HTTPMethod = "GET"
+			tt.event.Path = "/"
+
+			req, err := ConvertALBTargetGroupRequest(context.Background(), tt.event)
+			if err != nil {
+				t.Fatalf("ConvertALBTargetGroupRequest failed: %v", err)
+			}
+
+			if req.RemoteAddr != tt.expected {
+				t.Errorf("expected RemoteAddr %s, got %s", tt.expected, req.RemoteAddr)
+			}
+		})
+	}
+}
+
+func TestConvertResponseALB(t *testing.T) {
+	data := ResponseData{
+		StatusCode: http.StatusNotFound,
+		Headers: http.Header{
+			"Content-Type": []string{"application/json"},
+			"Vary":         []string{"Accept", "Origin"},
+			"Set-Cookie":   []string{"cookie1=value1", "cookie2=value2"},
+		},
+		Body: []byte(`{"message":"not found"}`),
+	}
+
+	resp, err := ConvertResponseALB(data)
+	if err != nil {
+		t.Fatalf("ConvertResponseALB failed: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status code %d, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+
+	if resp.StatusDescription != "404 Not Found" {
+		t.Errorf("expected status description %q, got %q", "404 Not Found", resp.StatusDescription)
+	}
+
+	if resp.Headers["Vary"] != "Accept, Origin" {
+		t.Errorf("expected Vary %q, got %q", "Accept, Origin", resp.Headers["Vary"])
+	}
+
+	if resp.Headers["Set-Cookie"] != "cookie1=value1" {
+		t.Errorf("expected Set-Cookie %q, got %q", "cookie1=value1", resp.Headers["Set-Cookie"])
+	}
+
+	if resp.MultiValueHeaders != nil {
+		t.Errorf("expected no multi-value headers, got %v", resp.MultiValueHeaders)
+	}
+
+	if resp.IsBase64Encoded {
+		t.Errorf("expected a text body")
+	}
+
+	if resp.Body != `{"message":"not found"}` {
+		t.Errorf("expected body %q, got %q", `{"message":"not found"}`, resp.Body)
+	}
+}
+
+func TestGateway_InvokeALB_MultiValue(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Set-Cookie", "cookie1=value1")
+		w.Header().Add("Set-Cookie", "cookie2=value2")
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("Hello, World!"))
+	})
+
+	gw := NewGateway(handler, ConvertALBTargetGroupRequest, ConvertResponseALB)
+
+	payload := []byte(`{
+		"httpMethod": "GET",
+		"path": "/",
+		"multiValueQueryStringParameters": {},
+		"multiValueHeaders": {"host": ["example.com"]},
+		"requestContext": {"elb": {"targetGroupArn": "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/test/abc"}},
+		"isBase64Encoded": false,
+		"body": ""
+	}`)
+
+	respPayload, err := gw.Invoke(context.Background(), payload)
+	if err != nil {
+		t.Fatalf("Invoke failed: %v", err)
+	}
+
+	var resp events.ALBTargetGroupResponse
+	if err := json.Unmarshal(respPayload, &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if resp.StatusDescription != "200 OK" {
+		t.Errorf("expected status description %q, got %q", "200 OK", resp.StatusDescription)
+	}
+
+	if len(resp.Headers) != 0 {
+		t.Errorf("expected no single-value headers, got %v", resp.Headers)
+	}
+
+	cookies := resp.MultiValueHeaders["Set-Cookie"]
+	if !equalStringSlices(cookies, []string{"cookie1=value1", "cookie2=value2"}) {
+		t.Errorf("expected Set-Cookie [cookie1=value1, cookie2=value2], got %v", cookies)
+	}
+
+	if resp.Body != "Hello, World!" {
+		t.Errorf("expected body %q, got %q", "Hello, World!", resp.Body)
+	}
+}
@@ -0,0 +1,136 @@
+package internal
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+// ===========================
+// Base Path Stripping
+// ===========================
+
+// BasePath holds the fields of an event a BasePathPolicy decides from.
+type BasePath struct {
+	// Path is the request path as the event delivered it.
+	Path string
+	// Stage is the API Gateway stage, empty for ALB and Function URL events.
+	Stage string
+	// DomainName is the domain the request was sent to.
+	DomainName string
+	// Resource is the route template API Gateway matched, such as /orders/{id}.
+	Resource string
+	// PathParameters holds the values API Gateway matched for Resource.
+	PathParameters map[string]string
+}
+
+// BasePathPolicy returns the prefix of the request path to strip before routing.
+type BasePathPolicy func(BasePath) string
+
+// StripStage strips the stage API Gateway puts in front of the path of
+// non-$default stages.
+func StripStage() BasePathPolicy {
+	return func(bp BasePath) string {
+		if bp.Stage == "" || bp.Stage == "$default" {
+			return ""
+		}
+		return "/" + bp.Stage
+	}
+}
+
+// StripPrefix strips a fixed prefix, such as the base path of a custom domain mapping.
+func StripPrefix(prefix string) BasePathPolicy {
+	return func(BasePath) string {
+		return prefix
+	}
+}
+
+// DetectBasePath strips whatever precedes the part of the path matched by the
+// route template, which covers stages and custom domain mappings alike. Without
+// a template the stage is stripped on execute-api domains.
+func DetectBasePath() BasePathPolicy {
+	stage := StripStage()
+	return func(bp BasePath) string {
+		if prefix, ok := templatePrefix(bp); ok {
+			return prefix
+		}
+		if strings.Contains(bp.DomainName, ".execute-api.") {
+			return stage(bp)
+		}
+		return ""
+	}
+}
+
+// templatePrefix expands the route template with the path parameters and
+// returns what precedes it in the path. REST APIs send the parameters escaped
+// and the path decoded, so the parameters are also tried unescaped.
+func templatePrefix(bp BasePath) (string, bool) {
+	for _, unescape := range []bool{false, true} {
+		matched, ok := expandTemplate(bp, unescape)
+		if ok && strings.HasSuffix(bp.Path, matched) {
+			return strings.TrimSuffix(bp.Path, matched), true
+		}
+	}
+	return "", false
+}
+
+// expandTemplate fills the placeholders of the route template with the path
+// parameters, unescaped if asked to.
+func expandTemplate(bp BasePath, unescape bool) (string, bool) {
+	if !strings.HasPrefix(bp.Resource, "/") {
+		return "", false
+	}
+
+	segments := strings.Split(bp.Resource, "/")
+	for i, seg := range segments {
+		if !strings.HasPrefix(seg, "{") || !strings.HasSuffix(seg, "}") {
+			continue
+		}
+		name := seg[1 : len(seg)-1]
+		v, ok := bp.PathParameters[name]
+		if !ok {
+			v, ok = bp.PathParameters[strings.TrimSuffix(name, "+")]
+		}
+		if !ok {
+			return "", false
+		}
+		if unescape {
+			if unescaped, err := url.PathUnescape(v); err == nil {
+				v = unescaped
+			}
+		}
+		segments[i] = v
+	}
+
+	return strings.TrimSuffix(strings.Join(segments, "/"), "/"), true
+}
+
+// stripBasePath removes the prefix chosen by the BasePath policy of the gateway
+// from u and records it in the returned context.
+func stripBasePath(ctx context.Context, u *url.URL, bp BasePath) context.Context {
+	policy := OptionsFromContext(ctx).BasePath
+	if policy == nil {
+		return ctx
+	}
+
+	prefix := strings.TrimSuffix(policy(bp), "/")
+	if prefix == "" || (u.Path != prefix && !strings.HasPrefix(u.Path, prefix+"/")) {
+		return ctx
+	}
+
+	if u.RawPath != "" {
+		u.RawPath = strings.TrimPrefix(u.RawPath, (&url.URL{Path: prefix}).EscapedPath())
+	}
+	u.Path = strings.TrimPrefix(u.Path, prefix)
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	return context.WithValue(ctx, basePathKey, prefix)
+}
+
+// BasePathFromContext returns the prefix stripped from the request path, if any.
+func BasePathFromContext(ctx context.Context) string {
+	prefix, _ := ctx.Value(basePathKey).(string)
+	return prefix
+}
@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestDetectBasePath(t *testing.T) {
+	tests := []struct {
+		name     string
+		bp       BasePath
+		expected string
+	}{
+		{
+			name:     "custom domain mapping",
+			bp:       BasePath{Path: "/v1/orders/42", DomainName: "api.example.com", Resource: "/orders/{id}", PathParameters: map[string]string{"id": "42"}},
+			expected: "/v1",
+		},
+		{
+			name:     "greedy proxy",
+			bp:       BasePath{Path: "/v1/files/a/b.txt", DomainName: "api.example.com", Resource: "/files/{proxy+}", PathParameters: map[string]string{"proxy": "a/b.txt"}},
+			expected: "/v1",
+		},
+		{
+			name:     "escaped path parameter",
+			bp:       BasePath{Path: "/v1/files/a b/c", DomainName: "api.example.com", Resource: "/files/{key}", PathParameters: map[string]string{"key": "a%20b%2Fc"}},
+			expected: "/v1",
+		},
+		{
+			name:     "root resource",
+			bp:       BasePath{Path: "/v1", DomainName: "api.example.com", Resource: "/"},
+			expected: "/v1",
+		},
+		{
+			name:     "execute-api domain",
+			bp:       BasePath{Path: "/orders/42", Stage: "prod", DomainName: "abc.execute-api.us-east-1.amazonaws.com", Resource: "/orders/{id}", PathParameters: map[string]string{"id": "42"}},
+			expected: "",
+		},
+		{
+			name:     "default route",
+			bp:       BasePath{Path: "/prod/orders", Stage: "prod", DomainName: "abc.execute-api.us-east-1.amazonaws.com"},
+			expected: "/prod",
+		},
+		{
+			name:     "unknown custom domain route",
+			bp:       BasePath{Path: "/v1/orders", Stage: "prod", DomainName: "api.example.com"},
+			expected: "",
+		},
+	}
+
+	detect := DetectBasePath()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detect(tt.bp); got != tt.expected {
+				t.Errorf("expected prefix %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestConvertRequest_BasePath(t *testing.T) {
+	ctx := NewOptionsContext(context.Background(), &Options{BasePath: StripStage()})
+
+	req, err := ConvertAPIGatewayV2HTTPRequest(ctx, events.APIGatewayV2HTTPRequest{
+		RouteKey:       "GET /orders",
+		RawPath:        "/prod/orders",
+		RawQueryString: "page=2",
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			Stage: "prod",
+			HTTP:  events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ConvertAPIGatewayV2HTTPRequest failed: %v", err)
+	}
+
+	if req.URL.Path != "/orders" {
+		t.Errorf("expected path /orders, got %s", req.URL.Path)
+	}
+
+	if req.RequestURI != "/orders?page=2" {
+		t.Errorf("expected request URI /orders?page=2, got %s", req.RequestURI)
+	}
+
+	if prefix := BasePathFromContext(req.Context()); prefix != "/prod" {
+		t.Errorf("expected the stripped prefix /prod in the context, got %q", prefix)
+	}
+
+	// A prefix that only matches part of a segment is left alone
+	ctx = NewOptionsContext(context.Background(), &Options{BasePath: StripPrefix("/v1")})
+
+	req, err = ConvertALBTargetGroupRequest(ctx, events.ALBTargetGroupRequest{HTTPMethod: "GET", Path: "/v10/orders"})
+	if err != nil {
+		t.Fatalf("ConvertALBTargetGroupRequest failed: %v", err)
+	}
+
+	if req.URL.Path != "/v10/orders" {
+		t.Errorf("expected path /v10/orders, got %s", req.URL.Path)
+	}
+
+	if prefix := BasePathFromContext(req.Context()); prefix != "" {
+		t.Errorf("expected no stripped prefix, got %q", prefix)
+	}
+}
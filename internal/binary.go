@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ===========================
+// Binary Detection
+// ===========================
+
+// BinaryHeader lets a handler decide whether its response body is sent base64
+// encoded with "true" or "false". The header is removed from the response.
+const BinaryHeader = "X-Gateway-Binary"
+
+// BinaryPolicy reports whether a response with the given headers has a binary body.
+type BinaryPolicy func(http.Header) bool
+
+// BinaryMediaTypes returns a BinaryPolicy that, like the binaryMediaTypes of a
+// REST API, treats bodies of the binary types as binary and those of the text
+// types as text. Patterns may end in a wildcard, such as "image/*" or "*/*".
+// Other types are detected as usual and compressed bodies are always binary.
+func BinaryMediaTypes(binary, text []string) BinaryPolicy {
+	return func(h http.Header) bool {
+		if isEncoded(h) {
+			return true
+		}
+
+		mt, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+		switch {
+		case err != nil:
+		case matchMediaType(mt, binary):
+			return true
+		case matchMediaType(mt, text):
+			return false
+		}
+		return isBinary(h)
+	}
+}
+
+// matchMediaType reports whether mt matches one of the patterns.
+func matchMediaType(mt string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if mt == pattern {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(mt, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isEncoded reports whether the body has a Content-Encoding other than identity.
+func isEncoded(h http.Header) bool {
+	encoding := h.Get("Content-Encoding")
+	return encoding != "" && encoding != "identity"
+}
+
+// binaryBody decides whether the body of data is sent base64 encoded, from the
+// BinaryHeader of the handler, the BinaryPolicy of the gateway or the default
+// detection, and returns data without the BinaryHeader. An empty body is never
+// base64 encoded and an untyped body only if it is not valid UTF-8.
+func binaryBody(data ResponseData) (ResponseData, bool) {
+	if values, ok := data.Headers[BinaryHeader]; ok {
+		data.Headers = data.Headers.Clone()
+		data.Headers.Del(BinaryHeader)
+		if len(data.Body) == 0 {
+			return data, false
+		}
+		if len(values) > 0 {
+			if binary, err := strconv.ParseBool(values[0]); err == nil {
+				return data, binary
+			}
+		}
+	}
+
+	if len(data.Body) == 0 {
+		return data, false
+	}
+
+	// Without a type only valid UTF-8 survives being sent as a JSON string
+	if data.Headers.Get("Content-Type") == "" && !utf8.Valid(data.Body) {
+		return data, true
+	}
+
+	if data.Request != nil {
+		if policy := OptionsFromContext(data.Request.Context()).Binary; policy != nil {
+			return data, policy(data.Headers)
+		}
+	}
+	return data, isBinary(data.Headers)
+}
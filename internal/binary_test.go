@@ -0,0 +1,159 @@
+package internal
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestBinaryMediaTypes(t *testing.T) {
+	policy := BinaryMediaTypes([]string{"application/json", "font/*"}, []string{"application/octet-stream"})
+
+	tests := []struct {
+		contentType string
+		encoding    string
+		expected    bool
+	}{
+		{"application/json", "", true},
+		{"font/woff2", "", true},
+		{"application/octet-stream", "", false},
+		{"application/octet-stream", "gzip", true},
+		{"text/html", "", false},
+		{"image/png", "", true},
+	}
+
+	for _, tt := range tests {
+		h := http.Header{"Content-Type": {tt.contentType}}
+		if tt.encoding != "" {
+			h.Set("Content-Encoding", tt.encoding)
+		}
+		if got := policy(h); got != tt.expected {
+			t.Errorf("expected %v for %s (%s), got %v", tt.expected, tt.contentType, tt.encoding, got)
+		}
+	}
+}
+
+func TestAutoGateway_BinaryOverride(t *testing.T) {
+	tests := []struct {
+		name     string
+		override string
+		policy   BinaryPolicy
+		expected bool
+	}{
+		{"default", "", nil, false},
+		{"policy", "", BinaryMediaTypes([]string{"text/*"}, nil), true},
+		{"header wins over policy", "false", BinaryMediaTypes([]string{"text/*"}, nil), false},
+		{"header forces binary", "true", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gw := NewAutoGateway(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				if tt.override != "" {
+					w.Header().Set(BinaryHeader, tt.override)
+				}
+				w.Write([]byte("hello"))
+			}), func(o *Options) {
+				o.Binary = tt.policy
+			})
+
+			out, err := gw.Invoke(context.Background(), []byte(testEventV1))
+			if err != nil {
+				t.Fatalf("Invoke failed: %v", err)
+			}
+
+			var resp struct {
+				Headers           map[string]string   `json:"headers"`
+				MultiValueHeaders map[string][]string `json:"multiValueHeaders"`
+				IsBase64Encoded   bool                `json:"isBase64Encoded"`
+			}
+			if err := json.Unmarshal(out, &resp); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+
+			if resp.IsBase64Encoded != tt.expected {
+				t.Errorf("expected isBase64Encoded %v, got %v", tt.expected, resp.IsBase64Encoded)
+			}
+
+			if _, ok := resp.Headers[BinaryHeader]; ok {
+				t.Errorf("expected %s to be removed from the headers", BinaryHeader)
+			}
+
+			if _, ok := resp.MultiValueHeaders[BinaryHeader]; ok {
+				t.Errorf("expected %s to be removed from the multi-value headers", BinaryHeader)
+			}
+		})
+	}
+}
+
+func TestAutoGateway_BodyWithoutType(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string
+		encoded bool
+	}{
+		{
+			name: "suppressed content type",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header()["Content-Type"] = nil
+				w.Write([]byte("hello"))
+			},
+			body: "hello",
+		},
+		{
+			name: "suppressed content type with binary body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header()["Content-Type"] = nil
+				w.Write([]byte{0x89, 'P', 'N', 'G', 0xff})
+			},
+			body:    base64.StdEncoding.EncodeToString([]byte{0x89, 'P', 'N', 'G', 0xff}),
+			encoded: true,
+		},
+		{
+			name: "no content",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+		},
+		{
+			name:    "empty body",
+			handler: func(w http.ResponseWriter, r *http.Request) {},
+		},
+		{
+			name: "empty binary body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "image/png")
+				w.Header().Set(BinaryHeader, "true")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := NewAutoGateway(tt.handler).Invoke(context.Background(), []byte(testEventV1))
+			if err != nil {
+				t.Fatalf("Invoke failed: %v", err)
+			}
+
+			var resp struct {
+				Body            string `json:"body"`
+				IsBase64Encoded bool   `json:"isBase64Encoded"`
+			}
+			if err := json.Unmarshal(out, &resp); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+
+			if resp.IsBase64Encoded != tt.encoded {
+				t.Errorf("expected isBase64Encoded %v, got %v", tt.encoded, resp.IsBase64Encoded)
+			}
+
+			if resp.Body != tt.body {
+				t.Errorf("expected body %q, got %q", tt.body, resp.Body)
+			}
+		})
+	}
+}
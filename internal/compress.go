@@ -0,0 +1,159 @@
+package internal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ===========================
+// Response Compression
+// ===========================
+
+// DefaultCompressionMinSize is the smallest body compressed when Compression has no MinSize.
+const DefaultCompressionMinSize = 1024
+
+// Compression configures the compression of response bodies negotiated from
+// the Accept-Encoding header of the request.
+type Compression struct {
+	// MinSize is the smallest body worth compressing. Zero uses DefaultCompressionMinSize.
+	MinSize int
+	// ContentTypes lists the media types to compress, such as "application/json"
+	// or "text/*". When empty, textual types are compressed.
+	ContentTypes []string
+	// Brotli, when set, creates a brotli writer and makes "br" the preferred encoding.
+	Brotli func(io.Writer) io.WriteCloser
+}
+
+// encoders returns the supported encodings in order of preference.
+func (c *Compression) encoders() []string {
+	if c.Brotli != nil {
+		return []string{"br", "gzip", "deflate"}
+	}
+	return []string{"gzip", "deflate"}
+}
+
+// newWriter returns a writer compressing to w with the given encoding.
+func (c *Compression) newWriter(w io.Writer, encoding string) io.WriteCloser {
+	switch encoding {
+	case "br":
+		return c.Brotli(w)
+	case "deflate":
+		// HTTP deflate is the zlib format, not a raw deflate stream
+		return zlib.NewWriter(w)
+	default:
+		return gzip.NewWriter(w)
+	}
+}
+
+// compressible reports whether a response with the given content type is allowed.
+func (c *Compression) compressible(contentType string) bool {
+	if len(c.ContentTypes) == 0 {
+		return isTextMime(contentType)
+	}
+
+	mt, _, err := mime.ParseMediaType(contentType)
+	return err == nil && matchMediaType(mt, c.ContentTypes)
+}
+
+// Compress encodes the body of data with the best encoding the client accepts,
+// when the response is large enough, of an allowed type and not encoded yet.
+func (c *Compression) Compress(data ResponseData) (ResponseData, error) {
+	minSize := c.MinSize
+	if minSize == 0 {
+		minSize = DefaultCompressionMinSize
+	}
+
+	switch {
+	case data.Request == nil,
+		data.StatusCode == http.StatusNoContent || data.StatusCode == http.StatusNotModified || data.StatusCode == http.StatusPartialContent,
+		data.Headers.Get("Content-Encoding") != "",
+		strings.Contains(data.Headers.Get("Cache-Control"), "no-transform"),
+		!c.compressible(data.Headers.Get("Content-Type")):
+		return data, nil
+	}
+
+	// The response depends on Accept-Encoding from here on, even when sent as is
+	data.Headers = data.Headers.Clone()
+	addVary(data.Headers, "Accept-Encoding")
+
+	encoding := negotiateEncoding(data.Request, c.encoders())
+	if encoding == "" || len(data.Body) < minSize {
+		return data, nil
+	}
+	return compressResponse(data, encoding, c.newWriter)
+}
+
+// negotiateEncoding returns the supported encoding with the highest quality in
+// the Accept-Encoding header of r, preferring earlier ones on ties.
+func negotiateEncoding(r *http.Request, supported []string) string {
+	accepted := map[string]float64{}
+	for _, v := range r.Header.Values("Accept-Encoding") {
+		for _, coding := range strings.Split(v, ",") {
+			name, params, _ := strings.Cut(coding, ";")
+			q := 1.0
+			if v, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+				if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+					q = parsed
+				}
+			}
+			accepted[strings.ToLower(strings.TrimSpace(name))] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range supported {
+		q, ok := accepted[encoding]
+		if !ok {
+			q, ok = accepted["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// compressResponse returns a copy of data with its body compressed with encoding.
+func compressResponse(data ResponseData, encoding string, newWriter func(io.Writer, string) io.WriteCloser) (ResponseData, error) {
+	var buf bytes.Buffer
+	zw := newWriter(&buf, encoding)
+	if _, err := zw.Write(data.Body); err != nil {
+		return ResponseData{}, err
+	}
+	if err := zw.Close(); err != nil {
+		return ResponseData{}, err
+	}
+
+	headers := data.Headers.Clone()
+	headers.Set("Content-Encoding", encoding)
+	addVary(headers, "Accept-Encoding")
+	if headers.Get("Content-Length") != "" {
+		headers.Set("Content-Length", strconv.Itoa(buf.Len()))
+	}
+	// The compressed body is no longer byte for byte the one a strong ETag names
+	if etag := headers.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		headers.Set("ETag", "W/"+etag)
+	}
+
+	data.Headers = headers
+	data.Body = buf.Bytes()
+	return data, nil
+}
+
+// addVary adds name to the Vary header unless it is listed already.
+func addVary(h http.Header, name string) {
+	for _, v := range h.Values("Vary") {
+		for _, field := range strings.Split(v, ",") {
+			if f := strings.TrimSpace(field); f == "*" || strings.EqualFold(f, name) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", name)
+}
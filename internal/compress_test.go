@@ -0,0 +1,170 @@
+package internal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	supported := []string{"br", "gzip", "deflate"}
+
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{"gzip", "gzip"},
+		{"gzip, deflate, br", "br"},
+		{"deflate;q=1.0, gzip;q=0.5", "deflate"},
+		{"GZIP", "gzip"},
+		{"gzip;q=0", ""},
+		{"*", "br"},
+		{"*;q=0.1, gzip", "gzip"},
+		{"identity", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		r, _ := http.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept-Encoding", tt.header)
+		if got := negotiateEncoding(r, supported); got != tt.expected {
+			t.Errorf("expected %q for %q, got %q", tt.expected, tt.header, got)
+		}
+	}
+}
+
+func TestCompression_Compress(t *testing.T) {
+	large := []byte(strings.Repeat("hello ", 500))
+
+	tests := []struct {
+		name           string
+		compression    Compression
+		acceptEncoding string
+		contentType    string
+		body           []byte
+		encoding       string
+	}{
+		{"gzip", Compression{}, "gzip, deflate", "application/json", large, "gzip"},
+		{"deflate", Compression{}, "deflate", "text/html", large, "deflate"},
+		{"not accepted", Compression{}, "", "text/plain", large, ""},
+		{"below minimum size", Compression{}, "gzip", "text/plain", []byte("hello"), ""},
+		{"binary type", Compression{}, "gzip", "image/png", large, ""},
+		{"allowlisted type", Compression{ContentTypes: []string{"image/*"}}, "gzip", "image/svg+xml", large, "gzip"},
+		{"not allowlisted", Compression{ContentTypes: []string{"application/json"}}, "gzip", "text/plain", large, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/", nil)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+
+			data, err := tt.compression.Compress(ResponseData{
+				StatusCode: http.StatusOK,
+				Headers:    http.Header{"Content-Type": {tt.contentType}, "Etag": {`"v1"`}},
+				Body:       tt.body,
+				Request:    req,
+			})
+			if err != nil {
+				t.Fatalf("Compress failed: %v", err)
+			}
+
+			if got := data.Headers.Get("Content-Encoding"); got != tt.encoding {
+				t.Errorf("expected Content-Encoding %q, got %q", tt.encoding, got)
+			}
+
+			if tt.encoding == "" {
+				if !bytes.Equal(data.Body, tt.body) {
+					t.Errorf("expected the body to be left alone")
+				}
+				return
+			}
+
+			if data.Headers.Get("Vary") != "Accept-Encoding" {
+				t.Errorf("expected Vary Accept-Encoding, got %v", data.Headers["Vary"])
+			}
+
+			if data.Headers.Get("ETag") != `W/"v1"` {
+				t.Errorf("expected a weak ETag, got %s", data.Headers.Get("ETag"))
+			}
+
+			var zr io.Reader
+			if tt.encoding == "gzip" {
+				zr, err = gzip.NewReader(bytes.NewReader(data.Body))
+			} else {
+				zr, err = zlib.NewReader(bytes.NewReader(data.Body))
+			}
+			if err != nil {
+				t.Fatalf("failed to read the compressed body: %v", err)
+			}
+			plain, _ := io.ReadAll(zr)
+			if !bytes.Equal(plain, tt.body) {
+				t.Errorf("expected the compressed body to round-trip")
+			}
+		})
+	}
+}
+
+// upperWriter stands in for a brotli encoder.
+type upperWriter struct{ io.Writer }
+
+func (w upperWriter) Write(b []byte) (int, error) { return w.Writer.Write(bytes.ToUpper(b)) }
+func (w upperWriter) Close() error                { return nil }
+
+func TestAutoGateway_Compression(t *testing.T) {
+	body := strings.Repeat("hello ", 500)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(body))
+	})
+
+	gw := NewAutoGateway(handler, func(o *Options) {
+		o.Compression = &Compression{Brotli: func(w io.Writer) io.WriteCloser { return upperWriter{w} }}
+	})
+
+	tests := []struct {
+		name    string
+		payload string
+	}{
+		{"api gateway v1", strings.Replace(testEventV1, `"headers": {`, `"headers": {"Accept-Encoding": "br", `, 1)},
+		{"api gateway v2", strings.Replace(testEventV2, `"headers": {`, `"headers": {"accept-encoding": "br", `, 1)},
+		{"alb", strings.Replace(testEventALB, `"headers": {`, `"headers": {"accept-encoding": "br", `, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := gw.Invoke(context.Background(), []byte(tt.payload))
+			if err != nil {
+				t.Fatalf("Invoke failed: %v", err)
+			}
+
+			var resp struct {
+				Headers         map[string]string `json:"headers"`
+				Body            string            `json:"body"`
+				IsBase64Encoded bool              `json:"isBase64Encoded"`
+			}
+			if err := json.Unmarshal(out, &resp); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+
+			if !resp.IsBase64Encoded {
+				t.Errorf("expected a base64 encoded body")
+			}
+
+			if resp.Headers["Content-Encoding"] != "br" || resp.Headers["Vary"] != "Accept-Encoding" {
+				t.Errorf("expected Content-Encoding br and Vary Accept-Encoding, got %v", resp.Headers)
+			}
+
+			decoded, _ := base64.StdEncoding.DecodeString(resp.Body)
+			if string(decoded) != strings.ToUpper(body) {
+				t.Errorf("expected the body from the brotli writer")
+			}
+		})
+	}
+}
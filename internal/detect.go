@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ===========================
+// Event Format Detection
+// ===========================
+
+// EventFormat identifies the shape of an incoming Lambda event.
+type EventFormat int
+
+const (
+	FormatUnknown EventFormat = iota
+	FormatAPIGatewayV1
+	FormatAPIGatewayV2
+	FormatALB
+	FormatFunctionURL
+)
+
+// String returns a human readable name for the format.
+func (f EventFormat) String() string {
+	switch f {
+	case FormatAPIGatewayV1:
+		return "API Gateway V1"
+	case FormatAPIGatewayV2:
+		return "API Gateway V2"
+	case FormatALB:
+		return "ALB"
+	case FormatFunctionURL:
+		return "Function URL"
+	default:
+		return "unknown"
+	}
+}
+
+// eventProbe holds the fields that tell the event formats apart.
+type eventProbe struct {
+	Version        string `json:"version"`
+	HTTPMethod     string `json:"httpMethod"`
+	RequestContext struct {
+		ELB        json.RawMessage `json:"elb"`
+		HTTP       json.RawMessage `json:"http"`
+		DomainName string          `json:"domainName"`
+	} `json:"requestContext"`
+}
+
+// DetectFormat sniffs a raw payload and reports which event format it is.
+//
+//   - requestContext.elb is only sent by ALB target groups
+//   - version "2.0" (or requestContext.http) marks the V2 payload shared by
+//     HTTP APIs and Function URLs, told apart by the lambda-url domain name
+//   - httpMethod marks the V1 payload of REST APIs and HTTP APIs using payload format 1.0
+func DetectFormat(payload []byte) EventFormat {
+	var probe eventProbe
+	if err := json.Unmarshal(payload, &probe); err != nil {
+		return FormatUnknown
+	}
+
+	switch {
+	case probe.RequestContext.ELB != nil:
+		return FormatALB
+	case probe.Version == "2.0" || probe.RequestContext.HTTP != nil:
+		if strings.Contains(probe.RequestContext.DomainName, ".lambda-url.") {
+			return FormatFunctionURL
+		}
+		return FormatAPIGatewayV2
+	case probe.HTTPMethod != "":
+		return FormatAPIGatewayV1
+	default:
+		return FormatUnknown
+	}
+}
+
+// DefaultCodecs returns the codecs for every supported event format.
+func DefaultCodecs() map[EventFormat]Codec {
+	return map[EventFormat]Codec{
+		FormatAPIGatewayV1: NewCodec(ConvertAPIGatewayProxyRequest, ConvertResponseV1),
+		FormatAPIGatewayV2: NewCodec(ConvertAPIGatewayV2HTTPRequest, ConvertResponseV2),
+		FormatALB:          NewCodec(ConvertALBTargetGroupRequest, ConvertResponseALB),
+		FormatFunctionURL:  NewCodec(ConvertLambdaFunctionURLRequest, ConvertResponseFunctionURL),
+	}
+}
+
+// ===========================
+// AutoGateway Struct and Methods
+// ===========================
+
+// AutoGateway wraps an http.Handler and picks the converters for every
+// invocation from the format of the incoming event.
+type AutoGateway struct {
+	handler http.Handler
+	codecs  map[EventFormat]Codec
+	options Options
+}
+
+// NewAutoGateway creates a new AutoGateway that understands every supported event format
+func NewAutoGateway(handler http.Handler, opts ...Option) *AutoGateway {
+	gw := &AutoGateway{handler: handler, codecs: DefaultCodecs()}
+	for _, opt := range opts {
+		opt(&gw.options)
+	}
+	if gw.options.ErrorHandler == nil {
+		gw.options.ErrorHandler = DefaultErrorHandler
+	}
+	if gw.options.DeadlineMargin == 0 {
+		gw.options.DeadlineMargin = DefaultDeadlineMargin
+	}
+	if gw.options.MaxResponseSize == 0 {
+		gw.options.MaxResponseSize = DefaultMaxResponseSize
+	}
+	return gw
+}
+
+// Format returns the event format of the custom converters, or FormatUnknown
+// when every invocation is detected.
+func (gw *AutoGateway) Format() EventFormat {
+	return gw.options.Format
+}
+
+// Invoke detects the event format, converts the event to an HTTP request, processes it,
+// and converts the response back to the response format matching the event.
+func (gw *AutoGateway) Invoke(ctx context.Context, payload []byte) ([]byte, error) {
+	format := DetectFormat(payload)
+
+	codec := gw.codecs[format]
+	if gw.options.DecodeRequest != nil {
+		codec.DecodeRequest = gw.options.DecodeRequest
+	}
+	if gw.options.EncodeResponse != nil {
+		codec.EncodeResponse = gw.options.EncodeResponse
+	}
+	if codec.DecodeRequest == nil || codec.EncodeResponse == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedEvent, format)
+	}
+
+	ctx = NewOptionsContext(ctx, &gw.options)
+	ctx = context.WithValue(ctx, payloadKey, payload)
+
+	return invoke(ctx, gw.handler, payload, codec, &gw.options)
+}
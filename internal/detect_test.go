@@ -0,0 +1,213 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+const (
+	testEventV1 = `{
+		"resource": "/{proxy+}",
+		"path": "/hello",
+		"httpMethod": "GET",
+		"headers": {"Host": "abc.execute-api.us-east-1.amazonaws.com"},
+		"requestContext": {"stage": "prod", "requestId": "req-v1", "domainName": "abc.execute-api.us-east-1.amazonaws.com"},
+		"isBase64Encoded": false
+	}`
+	testEventV2 = `{
+		"version": "2.0",
+		"routeKey": "GET /hello",
+		"rawPath": "/hello",
+		"rawQueryString": "",
+		"headers": {"host": "abc.execute-api.us-east-1.amazonaws.com"},
+		"cookies": ["a=1"],
+		"requestContext": {"stage": "$default", "requestId": "req-v2", "domainName": "abc.execute-api.us-east-1.amazonaws.com", "http": {"method": "GET", "path": "/hello"}},
+		"isBase64Encoded": false
+	}`
+	testEventALB = `{
+		"httpMethod": "GET",
+		"path": "/hello",
+		"headers": {"host": "example.com"},
+		"requestContext": {"elb": {"targetGroupArn": "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/test/abc"}},
+		"isBase64Encoded": false,
+		"body": ""
+	}`
+	testEventFunctionURL = `{
+		"version": "2.0",
+		"routeKey": "$default",
+		"rawPath": "/hello",
+		"rawQueryString": "",
+		"headers": {"host": "abc123.lambda-url.us-east-1.on.aws"},
+		"requestContext": {"stage": "$default", "requestId": "req-url", "domainName": "abc123.lambda-url.us-east-1.on.aws", "http": {"method": "GET", "path": "/hello"}},
+		"isBase64Encoded": false
+	}`
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		expected EventFormat
+	}{
+		{"api gateway v1", testEventV1, FormatAPIGatewayV1},
+		{"api gateway v2", testEventV2, FormatAPIGatewayV2},
+		{"alb", testEventALB, FormatALB},
+		{"function url", testEventFunctionURL, FormatFunctionURL},
+		{"unrelated event", `{"Records": []}`, FormatUnknown},
+		{"invalid json", `{`, FormatUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectFormat([]byte(tt.payload)); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestAutoGateway_Invoke(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Add("Set-Cookie", "session=abc")
+		w.Write([]byte("Hello, World!"))
+	})
+
+	gw := NewAutoGateway(handler)
+
+	tests := []struct {
+		name    string
+		payload string
+		check   func(t *testing.T, resp map[string]any)
+	}{
+		{
+			name:    "api gateway v1",
+			payload: testEventV1,
+			check: func(t *testing.T, resp map[string]any) {
+				if _, ok := resp["multiValueHeaders"]; !ok {
+					t.Errorf("expected a V1 response with multiValueHeaders, got %v", resp)
+				}
+			},
+		},
+		{
+			name:    "api gateway v2",
+			payload: testEventV2,
+			check: func(t *testing.T, resp map[string]any) {
+				if _, ok := resp["cookies"]; !ok {
+					t.Errorf("expected a V2 response with cookies, got %v", resp)
+				}
+			},
+		},
+		{
+			name:    "alb",
+			payload: testEventALB,
+			check: func(t *testing.T, resp map[string]any) {
+				if resp["statusDescription"] != "200 OK" {
+					t.Errorf("expected an ALB response with statusDescription, got %v", resp)
+				}
+			},
+		},
+		{
+			name:    "function url",
+			payload: testEventFunctionURL,
+			check: func(t *testing.T, resp map[string]any) {
+				if _, ok := resp["multiValueHeaders"]; ok {
+					t.Errorf("expected a Function URL response without multiValueHeaders, got %v", resp)
+				}
+				if _, ok := resp["cookies"]; !ok {
+					t.Errorf("expected a Function URL response with cookies, got %v", resp)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			respPayload, err := gw.Invoke(context.Background(), []byte(tt.payload))
+			if err != nil {
+				t.Fatalf("Invoke failed: %v", err)
+			}
+
+			var resp map[string]any
+			if err := json.Unmarshal(respPayload, &resp); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+
+			if resp["statusCode"] != float64(http.StatusOK) {
+				t.Errorf("expected status code %d, got %v", http.StatusOK, resp["statusCode"])
+			}
+
+			if resp["body"] != "Hello, World!" {
+				t.Errorf("expected body %q, got %v", "Hello, World!", resp["body"])
+			}
+
+			tt.check(t, resp)
+		})
+	}
+}
+
+func TestAutoGateway_InvokeUnknown(t *testing.T) {
+	gw := NewAutoGateway(http.NotFoundHandler())
+
+	if _, err := gw.Invoke(context.Background(), []byte(`{"Records": []}`)); err == nil {
+		t.Errorf("expected an error for an unsupported event")
+	}
+}
+
+func TestAutoGateway_Options(t *testing.T) {
+	var decoded bool
+	gw := NewAutoGateway(http.NotFoundHandler(), func(o *Options) {
+		o.DecodeRequest = func(ctx context.Context, payload []byte) (*http.Request, error) {
+			decoded = true
+			return http.NewRequestWithContext(ctx, "GET", "/", nil)
+		}
+		o.EncodeResponse = func(data ResponseData) ([]byte, error) {
+			return []byte(http.StatusText(data.StatusCode)), nil
+		}
+	})
+
+	// Custom converters handle events the gateway would not detect
+	out, err := gw.Invoke(context.Background(), []byte(`{"Records": []}`))
+	if err != nil {
+		t.Fatalf("Invoke failed: %v", err)
+	}
+
+	if !decoded {
+		t.Errorf("expected the custom request decoder to be used")
+	}
+
+	if string(out) != "Not Found" {
+		t.Errorf("expected the custom response encoder to be used, got %q", string(out))
+	}
+}
+
+func TestAutoGateway_ErrorHandler(t *testing.T) {
+	var handled error
+	gw := NewAutoGateway(http.NotFoundHandler(), func(o *Options) {
+		o.ErrorHandler = func(ctx context.Context, err error) (ResponseData, error) {
+			handled = err
+			return ResponseData{StatusCode: http.StatusBadRequest}, nil
+		}
+	})
+
+	// A V1 event whose headers cannot be unmarshaled
+	out, err := gw.Invoke(context.Background(), []byte(`{"httpMethod": "GET", "headers": []}`))
+	if err != nil {
+		t.Fatalf("expected the error handler to answer, got %v", err)
+	}
+
+	if handled == nil {
+		t.Errorf("expected the error handler to be called")
+	}
+
+	var resp map[string]any
+	if err := json.Unmarshal(out, &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if resp["statusCode"] != float64(http.StatusBadRequest) {
+		t.Errorf("expected status code %d, got %v", http.StatusBadRequest, resp["statusCode"])
+	}
+}
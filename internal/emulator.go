@@ -0,0 +1,566 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/pkg/errors"
+)
+
+// ===========================
+// Local Emulator
+// ===========================
+
+const (
+	// emulatorEnv enables the local emulator when running outside of Lambda.
+	emulatorEnv = "GATEWAY_EMULATOR"
+
+	emulatorAccountID = "000000000000"
+	emulatorAPIID     = "local"
+	emulatorStage     = "local"
+)
+
+// Emulator is an http.Handler that stands in for API Gateway, an ALB or a Function URL
+// in front of a Lambda handler. Every request is turned into a faithful event payload,
+// passed to the handler as JSON, and the response event is written back as HTTP.
+type Emulator struct {
+	handler lambda.Handler
+	format  EventFormat
+}
+
+// NewEmulator creates a new Emulator that builds events of the given format for handler
+func NewEmulator(handler lambda.Handler, format EventFormat) *Emulator {
+	return &Emulator{handler: handler, format: format}
+}
+
+// ServeHTTP round-trips the request through the event format and the Lambda handler.
+func (em *Emulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	lc := &lambdacontext.LambdaContext{
+		AwsRequestID:       newRequestID(),
+		InvokedFunctionArn: "arn:aws:lambda:local:" + emulatorAccountID + ":function:local",
+	}
+	r = r.WithContext(lambdacontext.NewContext(r.Context(), lc))
+
+	payload, err := BuildEvent(em.format, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	respPayload, err := em.handler.Invoke(r.Context(), payload)
+	if err != nil {
+		log.Printf("gateway emulator: request %s: invoke failed: %v", lc.AwsRequestID, err)
+		writeEmulatorError(w)
+		return
+	}
+
+	resp, err := DecodeResponse(em.format, respPayload)
+	if err != nil {
+		log.Printf("gateway emulator: request %s: malformed response: %v", lc.AwsRequestID, err)
+		writeEmulatorError(w)
+		return
+	}
+	defer resp.Body.Close()
+
+	for k, values := range resp.Header {
+		for _, v := range values {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+// writeEmulatorError writes the response API Gateway sends when the integration fails.
+func writeEmulatorError(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadGateway)
+	w.Write([]byte(`{"message":"Internal Server Error"}`))
+}
+
+// emulatorFormat returns the format to emulate when GATEWAY_EMULATOR is set. Gateways
+// bound to one event type always emulate that type; otherwise the variable picks one
+// ("v1", "v2", "alb" or "url") and defaults to API Gateway V2.
+func emulatorFormat(native EventFormat) (EventFormat, bool) {
+	v := os.Getenv(emulatorEnv)
+	if v == "" {
+		return FormatUnknown, false
+	}
+	if native != FormatUnknown {
+		return native, true
+	}
+	if format := ParseEventFormat(v); format != FormatUnknown {
+		return format, true
+	}
+	return FormatAPIGatewayV2, true
+}
+
+// serveLocal serves outside of Lambda, through the Emulator when it is enabled.
+func serveLocal(addr string, handler http.Handler, gw lambda.Handler, native EventFormat) error {
+	if format, ok := emulatorFormat(native); ok {
+		log.Printf("gateway emulator: serving %s events on %s", format, addr)
+		return http.ListenAndServe(addr, NewEmulator(gw, format))
+	}
+	return http.ListenAndServe(addr, handler)
+}
+
+// ParseEventFormat parses the short name of an event format: "v1", "v2", "alb" or "url".
+func ParseEventFormat(s string) EventFormat {
+	switch strings.ToLower(s) {
+	case "v1", "apigatewayv1":
+		return FormatAPIGatewayV1
+	case "v2", "apigatewayv2":
+		return FormatAPIGatewayV2
+	case "alb":
+		return FormatALB
+	case "url", "functionurl":
+		return FormatFunctionURL
+	default:
+		return FormatUnknown
+	}
+}
+
+// FormatOf returns the event format handled by a gateway for events of type T.
+func FormatOf[T any]() EventFormat {
+	var evt T
+	switch any(evt).(type) {
+	case events.APIGatewayProxyRequest:
+		return FormatAPIGatewayV1
+	case events.APIGatewayV2HTTPRequest:
+		return FormatAPIGatewayV2
+	case events.ALBTargetGroupRequest:
+		return FormatALB
+	case events.LambdaFunctionURLRequest:
+		return FormatFunctionURL
+	default:
+		return FormatUnknown
+	}
+}
+
+// ===========================
+// Event Builders
+// ===========================
+
+// BuildEvent turns an *http.Request into the JSON payload of the given event format.
+func BuildEvent(format EventFormat, r *http.Request) ([]byte, error) {
+	var (
+		evt any
+		err error
+	)
+	switch format {
+	case FormatAPIGatewayV1:
+		evt, err = BuildAPIGatewayProxyRequest(r)
+	case FormatAPIGatewayV2:
+		evt, err = BuildAPIGatewayV2HTTPRequest(r)
+	case FormatALB:
+		evt, err = BuildALBTargetGroupRequest(r)
+	case FormatFunctionURL:
+		evt, err = BuildLambdaFunctionURLRequest(r)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedEvent, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(evt)
+}
+
+// BuildAPIGatewayProxyRequest turns an *http.Request into the event a REST API proxy integration sends.
+// Like a REST API it decodes the path but keeps the path parameters and the
+// request context path, which starts with the stage, escaped.
+func BuildAPIGatewayProxyRequest(r *http.Request) (events.APIGatewayProxyRequest, error) {
+	body, isBase64, err := eventBody(r)
+	if err != nil {
+		return events.APIGatewayProxyRequest{}, err
+	}
+
+	now := time.Now()
+	e := events.APIGatewayProxyRequest{
+		Resource:          "/{proxy+}",
+		Path:              r.URL.Path,
+		HTTPMethod:        r.Method,
+		Headers:           make(map[string]string),
+		MultiValueHeaders: make(map[string][]string),
+		PathParameters:    map[string]string{"proxy": strings.TrimPrefix(r.URL.EscapedPath(), "/")},
+		RequestContext: events.APIGatewayProxyRequestContext{
+			AccountID:        emulatorAccountID,
+			Stage:            emulatorStage,
+			DomainName:       r.Host,
+			DomainPrefix:     domainPrefix(r.Host),
+			RequestID:        eventRequestID(r),
+			Protocol:         r.Proto,
+			ResourcePath:     "/{proxy+}",
+			Path:             "/" + emulatorStage + r.URL.EscapedPath(),
+			HTTPMethod:       r.Method,
+			RequestTime:      now.Format("02/Jan/2006:15:04:05 -0700"),
+			RequestTimeEpoch: now.UnixMilli(),
+			APIID:            emulatorAPIID,
+			Identity: events.APIGatewayRequestIdentity{
+				SourceIP:  sourceIP(r),
+				UserAgent: r.UserAgent(),
+			},
+		},
+		Body:            body,
+		IsBase64Encoded: isBase64,
+	}
+
+	// The single-value fields carry the last value of each header and query parameter
+	for k, values := range requestHeaders(r) {
+		e.Headers[k] = values[len(values)-1]
+		e.MultiValueHeaders[k] = values
+	}
+	if q := r.URL.Query(); len(q) > 0 {
+		e.QueryStringParameters = make(map[string]string, len(q))
+		e.MultiValueQueryStringParameters = q
+		for k, values := range q {
+			e.QueryStringParameters[k] = values[len(values)-1]
+		}
+	}
+
+	return e, nil
+}
+
+// BuildAPIGatewayV2HTTPRequest turns an *http.Request into the event an HTTP API sends with payload format 2.0.
+func BuildAPIGatewayV2HTTPRequest(r *http.Request) (events.APIGatewayV2HTTPRequest, error) {
+	body, isBase64, err := eventBody(r)
+	if err != nil {
+		return events.APIGatewayV2HTTPRequest{}, err
+	}
+
+	headers, cookies := foldRequestHeaders(r)
+
+	now := time.Now()
+	return events.APIGatewayV2HTTPRequest{
+		Version:               "2.0",
+		RouteKey:              "$default",
+		RawPath:               r.URL.EscapedPath(),
+		RawQueryString:        r.URL.RawQuery,
+		Cookies:               cookies,
+		Headers:               headers,
+		QueryStringParameters: foldQuery(r),
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			RouteKey:     "$default",
+			AccountID:    emulatorAccountID,
+			Stage:        "$default",
+			RequestID:    eventRequestID(r),
+			APIID:        emulatorAPIID,
+			DomainName:   r.Host,
+			DomainPrefix: domainPrefix(r.Host),
+			Time:         now.Format("02/Jan/2006:15:04:05 -0700"),
+			TimeEpoch:    now.UnixMilli(),
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				Protocol:  r.Proto,
+				SourceIP:  sourceIP(r),
+				UserAgent: r.UserAgent(),
+			},
+		},
+		Body:            body,
+		IsBase64Encoded: isBase64,
+	}, nil
+}
+
+// BuildALBTargetGroupRequest turns an *http.Request into the event an ALB target group
+// sends with multi-value headers disabled. Query parameters are passed through undecoded.
+func BuildALBTargetGroupRequest(r *http.Request) (events.ALBTargetGroupRequest, error) {
+	body, isBase64, err := eventBody(r)
+	if err != nil {
+		return events.ALBTargetGroupRequest{}, err
+	}
+
+	e := events.ALBTargetGroupRequest{
+		HTTPMethod: r.Method,
+		Path:       r.URL.EscapedPath(),
+		Headers:    make(map[string]string),
+		RequestContext: events.ALBTargetGroupRequestContext{
+			ELB: events.ELBContext{
+				TargetGroupArn: "arn:aws:elasticloadbalancing:local:" + emulatorAccountID + ":targetgroup/local/0000000000000000",
+			},
+		},
+		Body:            body,
+		IsBase64Encoded: isBase64,
+	}
+
+	for k, values := range requestHeaders(r) {
+		e.Headers[strings.ToLower(k)] = values[len(values)-1]
+	}
+	// Like ALB, append the client address to any X-Forwarded-For the client sent
+	if xff := strings.Join(r.Header.Values("X-Forwarded-For"), ", "); xff != "" {
+		e.Headers["x-forwarded-for"] = xff + ", " + sourceIP(r)
+	} else {
+		e.Headers["x-forwarded-for"] = sourceIP(r)
+	}
+	e.Headers["x-forwarded-proto"] = "http"
+	if _, port, err := net.SplitHostPort(r.Host); err == nil {
+		e.Headers["x-forwarded-port"] = port
+	}
+
+	if r.URL.RawQuery != "" {
+		e.QueryStringParameters = make(map[string]string)
+		for _, pair := range strings.Split(r.URL.RawQuery, "&") {
+			k, v, _ := strings.Cut(pair, "=")
+			e.QueryStringParameters[k] = v
+		}
+	}
+
+	return e, nil
+}
+
+// BuildLambdaFunctionURLRequest turns an *http.Request into the event a Function URL sends.
+func BuildLambdaFunctionURLRequest(r *http.Request) (events.LambdaFunctionURLRequest, error) {
+	body, isBase64, err := eventBody(r)
+	if err != nil {
+		return events.LambdaFunctionURLRequest{}, err
+	}
+
+	headers, cookies := foldRequestHeaders(r)
+
+	now := time.Now()
+	return events.LambdaFunctionURLRequest{
+		Version:               "2.0",
+		RawPath:               r.URL.EscapedPath(),
+		RawQueryString:        r.URL.RawQuery,
+		Cookies:               cookies,
+		Headers:               headers,
+		QueryStringParameters: foldQuery(r),
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			AccountID:    emulatorAccountID,
+			RequestID:    eventRequestID(r),
+			APIID:        emulatorAPIID,
+			DomainName:   emulatorAPIID + ".lambda-url.local.on.aws",
+			DomainPrefix: emulatorAPIID,
+			Time:         now.Format("02/Jan/2006:15:04:05 -0700"),
+			TimeEpoch:    now.UnixMilli(),
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				Protocol:  r.Proto,
+				SourceIP:  sourceIP(r),
+				UserAgent: r.UserAgent(),
+			},
+		},
+		Body:            body,
+		IsBase64Encoded: isBase64,
+	}, nil
+}
+
+// eventBody reads the request body, base64 encoding it unless it is textual.
+func eventBody(r *http.Request) (string, bool, error) {
+	if r.Body == nil {
+		return "", false, nil
+	}
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", false, errors.Wrap(err, "reading body")
+	}
+	r.Body = io.NopCloser(bytes.NewReader(b))
+
+	if len(b) == 0 {
+		return "", false, nil
+	}
+	if isTextMime(r.Header.Get("Content-Type")) {
+		return string(b), false, nil
+	}
+	return base64.StdEncoding.EncodeToString(b), true, nil
+}
+
+// requestHeaders returns the request headers including Host, which net/http moves to r.Host.
+func requestHeaders(r *http.Request) http.Header {
+	h := r.Header.Clone()
+	if h == nil {
+		h = make(http.Header)
+	}
+	if r.Host != "" {
+		h.Set("Host", r.Host)
+	}
+	return h
+}
+
+// foldRequestHeaders lowercases the header names and comma-joins repeated values
+// the way V2 payloads do. Cookies are split into their own list.
+func foldRequestHeaders(r *http.Request) (map[string]string, []string) {
+	headers := make(map[string]string)
+	var cookies []string
+
+	for k, values := range requestHeaders(r) {
+		if http.CanonicalHeaderKey(k) == "Cookie" {
+			for _, v := range values {
+				for _, c := range strings.Split(v, ";") {
+					if c = strings.TrimSpace(c); c != "" {
+						cookies = append(cookies, c)
+					}
+				}
+			}
+			continue
+		}
+		headers[strings.ToLower(k)] = strings.Join(values, ",")
+	}
+
+	return headers, cookies
+}
+
+// foldQuery comma-joins repeated query parameters the way V2 payloads do.
+func foldQuery(r *http.Request) map[string]string {
+	q := r.URL.Query()
+	if len(q) == 0 {
+		return nil
+	}
+	params := make(map[string]string, len(q))
+	for k, values := range q {
+		params[k] = strings.Join(values, ",")
+	}
+	return params
+}
+
+// sourceIP returns the client address without its port.
+func sourceIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// domainPrefix returns the first label of the host name.
+func domainPrefix(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	prefix, _, _ := strings.Cut(host, ".")
+	return prefix
+}
+
+// eventRequestID reuses the Lambda request ID from the context so the event and the
+// invocation agree, and generates a new one otherwise.
+func eventRequestID(r *http.Request) string {
+	if lc, ok := lambdacontext.FromContext(r.Context()); ok && lc.AwsRequestID != "" {
+		return lc.AwsRequestID
+	}
+	return newRequestID()
+}
+
+// newRequestID returns a random request ID in the UUID format Lambda uses.
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	s := hex.EncodeToString(b)
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:32]
+}
+
+// ===========================
+// Response Decoders
+// ===========================
+
+// DecodeResponse turns the JSON response payload of the given event format into an *http.Response,
+// applying the same header rules the AWS service does.
+func DecodeResponse(format EventFormat, payload []byte) (*http.Response, error) {
+	var (
+		statusCode int
+		header     = make(http.Header)
+		body       string
+		isBase64   bool
+	)
+
+	switch format {
+	case FormatAPIGatewayV1:
+		var resp events.APIGatewayProxyResponse
+		if err := json.Unmarshal(payload, &resp); err != nil {
+			return nil, errors.Wrap(err, "unmarshaling response")
+		}
+		statusCode, body, isBase64 = resp.StatusCode, resp.Body, resp.IsBase64Encoded
+		// Values in multiValueHeaders win over the same key in headers
+		for k, v := range resp.Headers {
+			if _, ok := resp.MultiValueHeaders[k]; !ok {
+				header.Add(k, v)
+			}
+		}
+		for k, values := range resp.MultiValueHeaders {
+			for _, v := range values {
+				header.Add(k, v)
+			}
+		}
+	case FormatAPIGatewayV2:
+		var resp events.APIGatewayV2HTTPResponse
+		if err := json.Unmarshal(payload, &resp); err != nil {
+			return nil, errors.Wrap(err, "unmarshaling response")
+		}
+		statusCode, body, isBase64 = resp.StatusCode, resp.Body, resp.IsBase64Encoded
+		// HTTP APIs ignore multiValueHeaders
+		for k, v := range resp.Headers {
+			header.Add(k, v)
+		}
+		for _, c := range resp.Cookies {
+			header.Add("Set-Cookie", c)
+		}
+	case FormatALB:
+		var resp events.ALBTargetGroupResponse
+		if err := json.Unmarshal(payload, &resp); err != nil {
+			return nil, errors.Wrap(err, "unmarshaling response")
+		}
+		statusCode, body, isBase64 = resp.StatusCode, resp.Body, resp.IsBase64Encoded
+		if resp.MultiValueHeaders != nil {
+			for k, values := range resp.MultiValueHeaders {
+				for _, v := range values {
+					header.Add(k, v)
+				}
+			}
+		} else {
+			for k, v := range resp.Headers {
+				header.Add(k, v)
+			}
+		}
+	case FormatFunctionURL:
+		var resp events.LambdaFunctionURLResponse
+		if err := json.Unmarshal(payload, &resp); err != nil {
+			return nil, errors.Wrap(err, "unmarshaling response")
+		}
+		statusCode, body, isBase64 = resp.StatusCode, resp.Body, resp.IsBase64Encoded
+		for k, v := range resp.Headers {
+			header.Add(k, v)
+		}
+		for _, c := range resp.Cookies {
+			header.Add("Set-Cookie", c)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedEvent, format)
+	}
+
+	b := []byte(body)
+	if isBase64 {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return nil, errors.Wrap(err, "decoding base64 body")
+		}
+		b = decoded
+	}
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+
+	return &http.Response{
+		Status:        strconv.Itoa(statusCode) + " " + http.StatusText(statusCode),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(b)),
+		ContentLength: int64(len(b)),
+	}, nil
+}
@@ -0,0 +1,295 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+func TestBuildAPIGatewayV2HTTPRequest(t *testing.T) {
+	r := httptest.NewRequest("POST", "http://example.com/a%2Fb/c?x=1&x=2&y=3", bytes.NewReader([]byte{0x89, 0x50, 0x4E, 0x47}))
+	r.Header.Set("Content-Type", "image/png")
+	r.Header.Add("Accept", "text/html")
+	r.Header.Add("Accept", "application/json")
+	r.Header.Add("Cookie", "a=1; b=2")
+
+	e, err := BuildAPIGatewayV2HTTPRequest(r)
+	if err != nil {
+		t.Fatalf("BuildAPIGatewayV2HTTPRequest failed: %v", err)
+	}
+
+	if e.RawPath != "/a%2Fb/c" {
+		t.Errorf("expected raw path /a%%2Fb/c, got %s", e.RawPath)
+	}
+
+	if e.RawQueryString != "x=1&x=2&y=3" {
+		t.Errorf("expected raw query x=1&x=2&y=3, got %s", e.RawQueryString)
+	}
+
+	if e.QueryStringParameters["x"] != "1,2" {
+		t.Errorf("expected query parameter x to be 1,2, got %q", e.QueryStringParameters["x"])
+	}
+
+	if e.Headers["accept"] != "text/html,application/json" {
+		t.Errorf("expected lowercased, comma-joined accept header, got %q", e.Headers["accept"])
+	}
+
+	if e.Headers["host"] != "example.com" {
+		t.Errorf("expected host header example.com, got %q", e.Headers["host"])
+	}
+
+	if _, ok := e.Headers["cookie"]; ok {
+		t.Errorf("expected cookies to be moved out of the headers")
+	}
+
+	if !equalStringSlices(e.Cookies, []string{"a=1", "b=2"}) {
+		t.Errorf("expected cookies [a=1, b=2], got %v", e.Cookies)
+	}
+
+	if !e.IsBase64Encoded || e.Body != base64.StdEncoding.EncodeToString([]byte{0x89, 0x50, 0x4E, 0x47}) {
+		t.Errorf("expected a base64 encoded binary body, got %q", e.Body)
+	}
+
+	if e.RequestContext.HTTP.Method != "POST" {
+		t.Errorf("expected method POST, got %s", e.RequestContext.HTTP.Method)
+	}
+}
+
+func TestBuildAPIGatewayProxyRequest(t *testing.T) {
+	r := httptest.NewRequest("GET", "http://example.com/test?x=1&x=2", nil)
+	r.Header.Add("X-Custom-Header", "value1")
+	r.Header.Add("X-Custom-Header", "value2")
+
+	e, err := BuildAPIGatewayProxyRequest(r)
+	if err != nil {
+		t.Fatalf("BuildAPIGatewayProxyRequest failed: %v", err)
+	}
+
+	if e.Headers["X-Custom-Header"] != "value2" {
+		t.Errorf("expected the last header value in headers, got %q", e.Headers["X-Custom-Header"])
+	}
+
+	if !equalStringSlices(e.MultiValueHeaders["X-Custom-Header"], []string{"value1", "value2"}) {
+		t.Errorf("expected all header values in multiValueHeaders, got %v", e.MultiValueHeaders["X-Custom-Header"])
+	}
+
+	if !equalStringSlices(e.MultiValueQueryStringParameters["x"], []string{"1", "2"}) {
+		t.Errorf("expected all query values, got %v", e.MultiValueQueryStringParameters["x"])
+	}
+
+	r = httptest.NewRequest("GET", "http://example.com/files/a%2Fb", nil)
+	if e, err = BuildAPIGatewayProxyRequest(r); err != nil {
+		t.Fatalf("BuildAPIGatewayProxyRequest failed: %v", err)
+	}
+
+	if e.Path != "/files/a/b" {
+		t.Errorf("expected the decoded path /files/a/b, got %q", e.Path)
+	}
+
+	if e.PathParameters["proxy"] != "files/a%2Fb" {
+		t.Errorf("expected the escaped proxy files/a%%2Fb, got %q", e.PathParameters["proxy"])
+	}
+
+	if e.RequestContext.Path != "/local/files/a%2Fb" {
+		t.Errorf("expected the request context path /local/files/a%%2Fb, got %q", e.RequestContext.Path)
+	}
+}
+
+func TestDecodeResponse(t *testing.T) {
+	payload, err := json.Marshal(events.APIGatewayProxyResponse{
+		StatusCode:        http.StatusCreated,
+		Headers:           map[string]string{"Content-Type": "application/json", "X-Single": "1"},
+		MultiValueHeaders: map[string][]string{"Content-Type": {"application/json"}, "Vary": {"Accept", "Origin"}},
+		Body:              base64.StdEncoding.EncodeToString([]byte(`{"ok":true}`)),
+		IsBase64Encoded:   true,
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal response: %v", err)
+	}
+
+	resp, err := DecodeResponse(FormatAPIGatewayV1, payload)
+	if err != nil {
+		t.Fatalf("DecodeResponse failed: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("expected status code %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
+
+	if !equalStringSlices(resp.Header["Content-Type"], []string{"application/json"}) {
+		t.Errorf("expected a single Content-Type, got %v", resp.Header["Content-Type"])
+	}
+
+	if !equalStringSlices(resp.Header["Vary"], []string{"Accept", "Origin"}) {
+		t.Errorf("expected Vary [Accept, Origin], got %v", resp.Header["Vary"])
+	}
+
+	if resp.Header.Get("X-Single") != "1" {
+		t.Errorf("expected X-Single 1, got %q", resp.Header.Get("X-Single"))
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != `{"ok":true}` {
+		t.Errorf("expected body %q, got %q", `{"ok":true}`, string(body))
+	}
+}
+
+func TestEmulator(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lc, ok := lambdacontext.FromContext(r.Context())
+		if !ok {
+			t.Errorf("expected a Lambda context")
+		} else if id := r.Header.Get("X-Request-Id"); id != "" && id != lc.AwsRequestID {
+			t.Errorf("expected the Lambda request ID %s to match the event request ID %s", lc.AwsRequestID, id)
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		w.Header().Add("Set-Cookie", "a=1")
+		w.Header().Add("Set-Cookie", "b=2")
+		w.Header().Set("X-Query", r.URL.Query().Get("q"))
+		w.WriteHeader(http.StatusAccepted)
+		w.Write(body)
+	})
+
+	tests := []struct {
+		name   string
+		format EventFormat
+	}{
+		{"api gateway v1", FormatAPIGatewayV1},
+		{"api gateway v2", FormatAPIGatewayV2},
+		{"alb", FormatALB},
+		{"function url", FormatFunctionURL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(NewEmulator(NewAutoGateway(handler), tt.format))
+			defer srv.Close()
+
+			binary := []byte{0x00, 0x01, 0xFF, 0xFE}
+			resp, err := http.Post(srv.URL+"/upload?q=hello", "application/octet-stream", bytes.NewReader(binary))
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusAccepted {
+				t.Errorf("expected status code %d, got %d", http.StatusAccepted, resp.StatusCode)
+			}
+
+			if resp.Header.Get("X-Query") != "hello" {
+				t.Errorf("expected X-Query hello, got %q", resp.Header.Get("X-Query"))
+			}
+
+			body, _ := io.ReadAll(resp.Body)
+			if !bytes.Equal(body, binary) {
+				t.Errorf("expected the binary body to round-trip, got %v", body)
+			}
+
+			if tt.format != FormatALB && len(resp.Header["Set-Cookie"]) != 2 {
+				t.Errorf("expected two cookies, got %v", resp.Header["Set-Cookie"])
+			}
+		})
+	}
+}
+
+func TestEmulator_EscapedPath(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Escaped-Path", r.URL.EscapedPath())
+		w.Header().Set("X-Proxy", r.PathValue("proxy"))
+	})
+	fidelity := func(o *Options) { o.URLFidelity = true }
+
+	srv := httptest.NewServer(NewEmulator(NewAutoGateway(handler, fidelity), FormatAPIGatewayV1))
+	defer srv.Close()
+
+	tests := []struct {
+		path        string
+		escapedPath string
+		proxy       string
+	}{
+		{"/files/a%2Fb", "/files/a%2Fb", "files/a/b"},
+		{"/files/a%252Fb", "/files/a%252Fb", "files/a%2Fb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			resp, err := http.Get(srv.URL + tt.path)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+
+			if got := resp.Header.Get("X-Escaped-Path"); got != tt.escapedPath {
+				t.Errorf("expected escaped path %q, got %q", tt.escapedPath, got)
+			}
+
+			if got := resp.Header.Get("X-Proxy"); got != tt.proxy {
+				t.Errorf("expected proxy %q, got %q", tt.proxy, got)
+			}
+		})
+	}
+}
+
+func TestEmulator_Errors(t *testing.T) {
+	failing := func(data ResponseData) (events.APIGatewayProxyResponse, error) {
+		return events.APIGatewayProxyResponse{}, io.ErrUnexpectedEOF
+	}
+
+	tests := []struct {
+		name     string
+		emulator *Emulator
+		expected int
+	}{
+		{"invoke error", NewEmulator(NewGateway(http.NotFoundHandler(), ConvertAPIGatewayProxyRequest, failing), FormatAPIGatewayV1), http.StatusBadGateway},
+		{"unsupported format", NewEmulator(NewAutoGateway(http.NotFoundHandler()), FormatUnknown), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.emulator.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status code %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestEmulatorFormat(t *testing.T) {
+	t.Setenv(emulatorEnv, "")
+	if _, ok := emulatorFormat(FormatUnknown); ok {
+		t.Errorf("expected the emulator to be disabled")
+	}
+
+	t.Setenv(emulatorEnv, "alb")
+	if format, ok := emulatorFormat(FormatUnknown); !ok || format != FormatALB {
+		t.Errorf("expected ALB, got %s", format)
+	}
+
+	if format, ok := emulatorFormat(FormatAPIGatewayV1); !ok || format != FormatAPIGatewayV1 {
+		t.Errorf("expected the native format to win, got %s", format)
+	}
+
+	t.Setenv(emulatorEnv, "true")
+	if format, ok := emulatorFormat(FormatUnknown); !ok || format != FormatAPIGatewayV2 {
+		t.Errorf("expected API Gateway V2 by default, got %s", format)
+	}
+
+	if FormatOf[events.ALBTargetGroupRequest]() != FormatALB {
+		t.Errorf("expected ALB events to map to the ALB format")
+	}
+
+	if !strings.Contains(FormatFunctionURL.String(), "Function URL") {
+		t.Errorf("unexpected format name %q", FormatFunctionURL.String())
+	}
+}
@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+// ===========================
+// Errors
+// ===========================
+
+// Errors returned for each stage of an invocation that can fail. The cause is
+// wrapped as well, so errors.Is and errors.As see both.
+var (
+	ErrUnsupportedEvent   = errors.New("unsupported event format")
+	ErrUnmarshal          = errors.New("failed to unmarshal payload")
+	ErrRequestConversion  = errors.New("failed to convert event to request")
+	ErrResponseConversion = errors.New("failed to convert response")
+	ErrHandlerTimeout     = errors.New("handler did not finish before the deadline")
+	ErrResponseTooLarge   = errors.New("response payload too large")
+	ErrUnfoldableHeader   = errors.New("repeated header cannot be comma-joined")
+)
+
+// PanicError is the error of an invocation whose handler panicked.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("handler panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error, such as http.ErrAbortHandler.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// DefaultErrorHandler logs err with the Lambda request ID and answers malformed
+// events with 400 Bad Request, handlers running into the deadline with 504
+// Gateway Timeout, oversized responses with 502 Bad Gateway and any other
+// failure, including a handler panic, with 500 Internal Server Error. Like net/http it logs the stack of a panic unless the
+// handler aborted with http.ErrAbortHandler.
+func DefaultErrorHandler(ctx context.Context, err error) (ResponseData, error) {
+	logError(ctx, err)
+
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrUnmarshal) || errors.Is(err, ErrRequestConversion):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrHandlerTimeout):
+		status = http.StatusGatewayTimeout
+	case errors.Is(err, ErrResponseTooLarge):
+		status = http.StatusBadGateway
+	}
+
+	return ResponseData{
+		StatusCode: status,
+		Headers:    http.Header{"Content-Type": {"application/json"}},
+		Body:       []byte(`{"message":"` + http.StatusText(status) + `"}`),
+	}, nil
+}
+
+// logError logs err with the Lambda request ID of ctx, and the stack of a handler
+// panic unless the handler aborted with http.ErrAbortHandler.
+func logError(ctx context.Context, err error) {
+	var requestID string
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		requestID = lc.AwsRequestID
+	}
+
+	var perr *PanicError
+	if !errors.As(err, &perr) {
+		log.Printf("gateway: request %s: %v", requestID, err)
+	} else if !errors.Is(err, http.ErrAbortHandler) {
+		log.Printf("gateway: request %s: %v\n%s", requestID, err, perr.Stack)
+	}
+}
@@ -0,0 +1,194 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAutoGateway_DefaultErrorHandler(t *testing.T) {
+	gw := NewAutoGateway(http.NotFoundHandler())
+
+	tests := []struct {
+		name    string
+		payload string
+		check   func(t *testing.T, resp map[string]any)
+	}{
+		{
+			name:    "unmarshal",
+			payload: `{"httpMethod": "GET", "headers": []}`,
+			check: func(t *testing.T, resp map[string]any) {
+				if _, ok := resp["multiValueHeaders"]; !ok {
+					t.Errorf("expected a V1 response, got %v", resp)
+				}
+			},
+		},
+		{
+			name:    "corrupt base64 body",
+			payload: `{"version": "2.0", "rawPath": "/", "body": "%%%", "isBase64Encoded": true, "requestContext": {"http": {"method": "POST"}}}`,
+			check: func(t *testing.T, resp map[string]any) {
+				if _, ok := resp["cookies"]; !ok {
+					t.Errorf("expected a V2 response, got %v", resp)
+				}
+			},
+		},
+		{
+			name:    "unparsable path",
+			payload: `{"httpMethod": "GET", "path": "%zz", "requestContext": {"elb": {}}}`,
+			check: func(t *testing.T, resp map[string]any) {
+				if resp["statusDescription"] != "400 Bad Request" {
+					t.Errorf("expected an ALB response, got %v", resp)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := gw.Invoke(context.Background(), []byte(tt.payload))
+			if err != nil {
+				t.Fatalf("expected an error response, got %v", err)
+			}
+
+			var resp map[string]any
+			if err := json.Unmarshal(out, &resp); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+
+			if resp["statusCode"] != float64(http.StatusBadRequest) {
+				t.Errorf("expected status code %d, got %v", http.StatusBadRequest, resp["statusCode"])
+			}
+
+			tt.check(t, resp)
+		})
+	}
+}
+
+func TestAutoGateway_ErrorStages(t *testing.T) {
+	failing := func(o *Options) {
+		o.EncodeResponse = NewResponseEncoder(func(data ResponseData) (struct{}, error) {
+			if data.StatusCode == http.StatusNotFound {
+				return struct{}{}, errors.New("boom")
+			}
+			return struct{}{}, nil
+		})
+	}
+
+	var stages []error
+	record := func(o *Options) {
+		o.ErrorHandler = func(ctx context.Context, err error) (ResponseData, error) {
+			for _, stage := range []error{ErrUnmarshal, ErrRequestConversion, ErrResponseConversion} {
+				if errors.Is(err, stage) {
+					stages = append(stages, stage)
+				}
+			}
+			return DefaultErrorHandler(ctx, err)
+		}
+	}
+
+	gw := NewAutoGateway(http.NotFoundHandler(), failing, record)
+	if _, err := gw.Invoke(context.Background(), []byte(testEventV2)); err != nil {
+		t.Fatalf("expected an error response, got %v", err)
+	}
+
+	if len(stages) != 1 || stages[0] != ErrResponseConversion {
+		t.Errorf("expected a single response conversion error, got %v", stages)
+	}
+
+	if _, err := gw.Invoke(context.Background(), []byte(`{"Records": []}`)); !errors.Is(err, ErrUnsupportedEvent) {
+		t.Errorf("expected ErrUnsupportedEvent, got %v", err)
+	}
+}
+
+func TestAutoGateway_Panic(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+	}{
+		{"panic", "boom"},
+		{"abort handler", http.ErrAbortHandler},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var handled error
+			gw := NewAutoGateway(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+				panic(tt.value)
+			}), func(o *Options) {
+				o.ErrorHandler = func(ctx context.Context, err error) (ResponseData, error) {
+					handled = err
+					return DefaultErrorHandler(ctx, err)
+				}
+			})
+
+			out, err := gw.Invoke(context.Background(), []byte(testEventV1))
+			if err != nil {
+				t.Fatalf("expected an error response, got %v", err)
+			}
+
+			var perr *PanicError
+			if !errors.As(handled, &perr) || perr.Value != tt.value || len(perr.Stack) == 0 {
+				t.Errorf("expected a PanicError for %v with a stack, got %v", tt.value, handled)
+			}
+
+			var resp map[string]any
+			if err := json.Unmarshal(out, &resp); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+
+			if resp["statusCode"] != float64(http.StatusInternalServerError) {
+				t.Errorf("expected status code %d, got %v", http.StatusInternalServerError, resp["statusCode"])
+			}
+
+			if _, ok := resp["multiValueHeaders"]; !ok {
+				t.Errorf("expected a V1 response, got %v", resp)
+			}
+		})
+	}
+}
+
+func TestAutoGateway_Deadline(t *testing.T) {
+	finished := make(chan struct{})
+	gw := NewAutoGateway(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(finished)
+
+		select {
+		case <-w.(http.CloseNotifier).CloseNotify():
+		case <-time.After(time.Second):
+			t.Errorf("expected CloseNotify to be signalled")
+		}
+
+		if r.Context().Err() == nil {
+			t.Errorf("expected the request context to be canceled")
+		}
+	}), func(o *Options) {
+		o.DeadlineMargin = 50 * time.Millisecond
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	out, err := gw.Invoke(ctx, []byte(testEventV2))
+	if err != nil {
+		t.Fatalf("expected an error response, got %v", err)
+	}
+
+	if ctx.Err() != nil {
+		t.Errorf("expected the response before the Lambda deadline")
+	}
+
+	var resp map[string]any
+	if err := json.Unmarshal(out, &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if resp["statusCode"] != float64(http.StatusGatewayTimeout) {
+		t.Errorf("expected status code %d, got %v", http.StatusGatewayTimeout, resp["statusCode"])
+	}
+
+	<-finished
+}
@@ -0,0 +1,164 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+// ===========================
+// Header Folding
+// ===========================
+
+// listHeaders are the headers RFC 9110 and its companions define as
+// comma-separated lists. Any other header is kept whole, since commas are part
+// of the value of headers such as User-Agent, Date or Authorization.
+var listHeaders = map[string]bool{
+	"Accept":                         true,
+	"Accept-Charset":                 true,
+	"Accept-Encoding":                true,
+	"Accept-Language":                true,
+	"Accept-Patch":                   true,
+	"Accept-Ranges":                  true,
+	"Access-Control-Allow-Headers":   true,
+	"Access-Control-Allow-Methods":   true,
+	"Access-Control-Expose-Headers":  true,
+	"Access-Control-Request-Headers": true,
+	"Allow":                          true,
+	"Cache-Control":                  true,
+	"Connection":                     true,
+	"Content-Encoding":               true,
+	"Content-Language":               true,
+	"Expect":                         true,
+	"Forwarded":                      true,
+	"If-Match":                       true,
+	"If-None-Match":                  true,
+	"Link":                           true,
+	"Pragma":                         true,
+	"Prefer":                         true,
+	"Proxy-Authenticate":             true,
+	"Server-Timing":                  true,
+	"Te":                             true,
+	"Trailer":                        true,
+	"Transfer-Encoding":              true,
+	"Upgrade":                        true,
+	"Vary":                           true,
+	"Via":                            true,
+	"Warning":                        true,
+	"Www-Authenticate":               true,
+	"X-Forwarded-For":                true,
+}
+
+// HeaderFolding registers headers, in addition to the RFC 9110 list headers,
+// whose comma-joined values are split back into separate values (List) or kept
+// whole (Singleton). The same rules decide which repeated response headers are
+// comma-joined for events without multi-value headers; Strict fails a response
+// with another repeated header instead of sending its first value.
+type HeaderFolding struct {
+	List      []string
+	Singleton []string
+	Strict    bool
+}
+
+// isList reports whether the values of the named header form a comma-separated list.
+func (f HeaderFolding) isList(name string) bool {
+	for _, s := range f.Singleton {
+		if strings.EqualFold(s, name) {
+			return false
+		}
+	}
+	for _, l := range f.List {
+		if strings.EqualFold(l, name) {
+			return true
+		}
+	}
+	return listHeaders[http.CanonicalHeaderKey(name)]
+}
+
+// Split returns the values of a header that the event joined with commas.
+func (f HeaderFolding) Split(name, value string) []string {
+	if !f.isList(name) {
+		return []string{value}
+	}
+	return splitList(value)
+}
+
+// Fold joins the values of a header into one comma-separated value. Repeated
+// headers that are not lists fail with ErrUnfoldableHeader.
+func (f HeaderFolding) Fold(name string, values []string) (string, error) {
+	if len(values) > 1 && !f.isList(name) {
+		return "", fmt.Errorf("%w: %s", ErrUnfoldableHeader, name)
+	}
+	return strings.Join(values, ", "), nil
+}
+
+// foldHeaders splits response headers into the single-value header map and
+// cookie list of API Gateway V2 and Function URL responses. A repeated header
+// that cannot be folded is logged and sent with its first value, unless the
+// folding of the gateway serving ctx is strict.
+func foldHeaders(ctx context.Context, h http.Header) (map[string]string, []string, error) {
+	folding := OptionsFromContext(ctx).HeaderFolding
+
+	headers := make(map[string]string, len(h))
+	cookies := []string{}
+
+	for k, values := range h {
+		if http.CanonicalHeaderKey(k) == "Set-Cookie" {
+			cookies = append(cookies, values...)
+			continue
+		}
+		if len(values) == 0 {
+			continue
+		}
+
+		v, err := folding.Fold(k, values)
+		if err != nil {
+			if folding.Strict {
+				return nil, nil, err
+			}
+			var requestID string
+			if lc, ok := lambdacontext.FromContext(ctx); ok {
+				requestID = lc.AwsRequestID
+			}
+			log.Printf("gateway: request %s: %v, sending the first value", requestID, err)
+			v = values[0]
+		}
+		headers[k] = v
+	}
+
+	return headers, cookies, nil
+}
+
+// splitList splits a comma-separated list on the commas outside quoted strings,
+// dropping the empty elements RFC 9110 allows.
+func splitList(value string) []string {
+	var (
+		values []string
+		quoted bool
+		start  int
+	)
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case c == '\\' && quoted:
+			i++
+		case c == '"':
+			quoted = !quoted
+		case c == ',' && !quoted:
+			values = appendElement(values, value[start:i])
+			start = i + 1
+		}
+	}
+	return appendElement(values, value[start:])
+}
+
+// appendElement appends a trimmed list element unless it is empty.
+func appendElement(values []string, element string) []string {
+	if element = strings.TrimSpace(element); element != "" {
+		values = append(values, element)
+	}
+	return values
+}
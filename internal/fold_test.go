@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestHeaderFolding_Split(t *testing.T) {
+	folding := HeaderFolding{List: []string{"x-tags"}, Singleton: []string{"Accept-Language"}}
+
+	tests := []struct {
+		name     string
+		header   string
+		value    string
+		expected []string
+	}{
+		{"list", "accept", "text/html, application/json;q=0.9", []string{"text/html", "application/json;q=0.9"}},
+		{"quoted etags", "If-None-Match", `"a,b", W/"c"`, []string{`"a,b"`, `W/"c"`}},
+		{"escaped quote", "If-Match", `"a\",b", "c"`, []string{`"a\",b"`, `"c"`}},
+		{"empty elements", "Cache-Control", "no-cache, , max-age=0,", []string{"no-cache", "max-age=0"}},
+		{"user agent", "User-Agent", "Mozilla/5.0 (KHTML, like Gecko)", []string{"Mozilla/5.0 (KHTML, like Gecko)"}},
+		{"date", "If-Modified-Since", "Wed, 21 Oct 2015 07:28:00 GMT", []string{"Wed, 21 Oct 2015 07:28:00 GMT"}},
+		{"authorization", "Authorization", `Digest username="a", realm="b"`, []string{`Digest username="a", realm="b"`}},
+		{"json", "X-Metadata", `{"a":1,"b":2}`, []string{`{"a":1,"b":2}`}},
+		{"custom list", "X-Tags", "a,b", []string{"a", "b"}},
+		{"custom singleton", "accept-language", "en, fr", []string{"en, fr"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := folding.Split(tt.header, tt.value); !equalStringSlices(got, tt.expected) {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestConvertAPIGatewayV2HTTPRequest_HeaderFolding(t *testing.T) {
+	ctx := NewOptionsContext(context.Background(), &Options{HeaderFolding: HeaderFolding{List: []string{"X-Tags"}}})
+
+	req, err := ConvertAPIGatewayV2HTTPRequest(ctx, events.APIGatewayV2HTTPRequest{
+		RawPath: "/",
+		Headers: map[string]string{
+			"accept":     "text/html,application/json",
+			"user-agent": "Mozilla/5.0 (KHTML, like Gecko)",
+			"x-tags":     "a,b",
+		},
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ConvertAPIGatewayV2HTTPRequest failed: %v", err)
+	}
+
+	if !equalStringSlices(req.Header["Accept"], []string{"text/html", "application/json"}) {
+		t.Errorf("expected Accept to be split, got %q", req.Header["Accept"])
+	}
+
+	if req.UserAgent() != "Mozilla/5.0 (KHTML, like Gecko)" {
+		t.Errorf("expected User-Agent to be kept whole, got %q", req.Header["User-Agent"])
+	}
+
+	if !equalStringSlices(req.Header["X-Tags"], []string{"a", "b"}) {
+		t.Errorf("expected the registered list header to be split, got %q", req.Header["X-Tags"])
+	}
+}
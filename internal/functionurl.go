@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/pkg/errors"
+)
+
+// ===========================
+// Function URL Request Converter
+// ===========================
+
+// ConvertLambdaFunctionURLRequest converts LambdaFunctionURLRequest to *http.Request.
+// Function URLs have no stage or route key, so unlike the V2 converter no X-Stage header is set.
+func ConvertLambdaFunctionURLRequest(ctx context.Context, e events.LambdaFunctionURLRequest) (*http.Request, error) {
+	// Parse the raw path
+	u, err := url.Parse(e.RawPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing raw path")
+	}
+
+	// Strip the base path chosen by the gateway options
+	ctx = stripBasePath(ctx, u, BasePath{Path: e.RawPath, DomainName: e.RequestContext.DomainName})
+
+	// Set the raw query string
+	u.RawQuery = e.RawQueryString
+
+	// Decode the body if it's base64 encoded
+	body := e.Body
+	if e.IsBase64Encoded {
+		b, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return nil, errors.Wrap(err, "decoding base64 body")
+		}
+		body = string(b)
+	}
+
+	// Create a new HTTP request
+	req, err := http.NewRequestWithContext(ctx, e.RequestContext.HTTP.Method, u.String(), strings.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "creating request")
+	}
+
+	// Manually set RequestURI
+	req.RequestURI = u.RequestURI()
+
+	// Set RemoteAddr
+	req.RemoteAddr = e.RequestContext.HTTP.SourceIP
+
+	// Set headers, splitting the values the event joined with commas
+	folding := OptionsFromContext(ctx).HeaderFolding
+	for k, values := range e.Headers {
+		for _, v := range folding.Split(k, values) {
+			req.Header.Add(k, v)
+		}
+	}
+	for _, c := range e.Cookies {
+		req.Header.Add("Cookie", c)
+	}
+
+	// Set Content-Length if not already set
+	if req.Header.Get("Content-Length") == "" && body != "" {
+		req.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	}
+
+	// Set custom headers
+	injectHeaders(ctx, req, map[ContextField]string{
+		FieldRequestID:  e.RequestContext.RequestID,
+		FieldAPIID:      e.RequestContext.APIID,
+		FieldDomainName: e.RequestContext.DomainName,
+		FieldAccountID:  e.RequestContext.AccountID,
+	})
+
+	// Add custom context values
+	req = req.WithContext(NewIdentityContext(NewContext(ctx, e), identityFunctionURL(e)))
+
+	// X-Ray support
+	if traceID := ctx.Value("x-amzn-trace-id"); traceID != nil {
+		req.Header.Set("X-Amzn-Trace-Id", fmt.Sprintf("%v", traceID))
+	}
+
+	// Set Host
+	req.URL.Host = req.Header.Get("Host")
+	if req.URL.Host == "" {
+		req.URL.Host = e.RequestContext.DomainName
+	}
+	req.Host = req.URL.Host
+
+	// Function URLs only accept HTTPS
+	setConnection(req, "https", e.RequestContext.HTTP.Protocol, "")
+
+	return req, nil
+}
+
+// ===========================
+// Function URL Response Converter
+// ===========================
+
+// ConvertResponseFunctionURL converts ResponseData to LambdaFunctionURLResponse.
+// Function URL responses have no multi-value headers, so repeated list headers are comma-joined.
+func ConvertResponseFunctionURL(data ResponseData) (events.LambdaFunctionURLResponse, error) {
+	data, isBin := binaryBody(data)
+
+	headers, cookies, err := foldHeaders(responseContext(data), data.Headers)
+	if err != nil {
+		return events.LambdaFunctionURLResponse{}, err
+	}
+
+	out := events.LambdaFunctionURLResponse{
+		StatusCode: data.StatusCode,
+		Headers:    headers,
+		Cookies:    cookies,
+	}
+
+	out.IsBase64Encoded = isBin
+
+	if isBin {
+		out.Body = base64.StdEncoding.EncodeToString(data.Body)
+	} else {
+		out.Body = string(data.Body)
+	}
+
+	return out, nil
+}
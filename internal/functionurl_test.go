@@ -0,0 +1,175 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestConvertLambdaFunctionURLRequest(t *testing.T) {
+	event := events.LambdaFunctionURLRequest{
+		Version:        "2.0",
+		RawPath:        "/test",
+		RawQueryString: "param=value",
+		Headers: map[string]string{
+			"content-type": "application/json",
+			"host":         "abc123.lambda-url.us-east-1.on.aws",
+		},
+		Cookies: []string{"cookie1=value1"},
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			RequestID:  "test-request-id",
+			DomainName: "abc123.lambda-url.us-east-1.on.aws",
+			Authorizer: &events.LambdaFunctionURLRequestContextAuthorizerDescription{
+				IAM: &events.LambdaFunctionURLRequestContextAuthorizerIAMDescription{
+					UserARN: "arn:aws:iam::123456789012:user/test",
+				},
+			},
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{
+				Method:   "POST",
+				SourceIP: "203.0.113.1",
+			},
+		},
+		Body: `{"key":"value"}`,
+	}
+
+	req, err := ConvertLambdaFunctionURLRequest(context.Background(), event)
+	if err != nil {
+		t.Fatalf("ConvertLambdaFunctionURLRequest failed: %v", err)
+	}
+
+	if req.Method != "POST" {
+		t.Errorf("expected method POST, got %s", req.Method)
+	}
+
+	if req.URL.Path != "/test" {
+		t.Errorf("expected path /test, got %s", req.URL.Path)
+	}
+
+	if req.URL.RawQuery != "param=value" {
+		t.Errorf("expected query param=value, got %s", req.URL.RawQuery)
+	}
+
+	if req.Host != "abc123.lambda-url.us-east-1.on.aws" {
+		t.Errorf("expected host abc123.lambda-url.us-east-1.on.aws, got %s", req.Host)
+	}
+
+	if req.RemoteAddr != "203.0.113.1" {
+		t.Errorf("expected RemoteAddr 203.0.113.1, got %s", req.RemoteAddr)
+	}
+
+	if req.Header.Get("X-Request-Id") != "test-request-id" {
+		t.Errorf("expected X-Request-Id test-request-id, got %s", req.Header.Get("X-Request-Id"))
+	}
+
+	if _, ok := req.Header["X-Stage"]; ok {
+		t.Errorf("expected no X-Stage header, got %q", req.Header.Get("X-Stage"))
+	}
+
+	if req.Header.Get("Cookie") != "cookie1=value1" {
+		t.Errorf("expected Cookie cookie1=value1, got %s", req.Header.Get("Cookie"))
+	}
+
+	e, ok := RequestContext[events.LambdaFunctionURLRequest](req.Context())
+	if !ok {
+		t.Fatalf("expected Function URL event in request context")
+	}
+
+	if e.RequestContext.Authorizer.IAM.UserARN != "arn:aws:iam::123456789012:user/test" {
+		t.Errorf("expected IAM user ARN in request context, got %q", e.RequestContext.Authorizer.IAM.UserARN)
+	}
+}
+
+func TestConvertResponseFunctionURL(t *testing.T) {
+	data := ResponseData{
+		StatusCode: http.StatusCreated,
+		Headers: http.Header{
+			"Content-Type": []string{"application/json"},
+			"Vary":         []string{"Accept", "Origin"},
+			"Location":     []string{"/a", "/b"},
+			"Set-Cookie":   []string{"cookie1=value1", "cookie2=value2"},
+		},
+		Body: []byte(`{"message":"created"}`),
+	}
+
+	resp, err := ConvertResponseFunctionURL(data)
+	if err != nil {
+		t.Fatalf("ConvertResponseFunctionURL failed: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("expected status code %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
+
+	if resp.Headers["Content-Type"] != "application/json" {
+		t.Errorf("expected Content-Type application/json, got %q", resp.Headers["Content-Type"])
+	}
+
+	if resp.Headers["Vary"] != "Accept, Origin" {
+		t.Errorf("expected Vary %q, got %q", "Accept, Origin", resp.Headers["Vary"])
+	}
+
+	if resp.Headers["Location"] != "/a" {
+		t.Errorf("expected the first Location /a, got %q", resp.Headers["Location"])
+	}
+
+	if _, ok := resp.Headers["Set-Cookie"]; ok {
+		t.Errorf("expected Set-Cookie to be moved to cookies")
+	}
+
+	if !equalStringSlices(resp.Cookies, []string{"cookie1=value1", "cookie2=value2"}) {
+		t.Errorf("expected cookies [cookie1=value1, cookie2=value2], got %v", resp.Cookies)
+	}
+
+	if resp.IsBase64Encoded {
+		t.Errorf("expected a text body")
+	}
+
+	if resp.Body != `{"message":"created"}` {
+		t.Errorf("expected body %q, got %q", `{"message":"created"}`, resp.Body)
+	}
+}
+
+func TestGateway_InvokeFunctionURL(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("Hello, World!"))
+	})
+
+	gw := NewGateway(handler, ConvertLambdaFunctionURLRequest, ConvertResponseFunctionURL)
+
+	event := events.LambdaFunctionURLRequest{
+		Version: "2.0",
+		RawPath: "/",
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{
+				Method: "GET",
+			},
+		},
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("failed to marshal event: %v", err)
+	}
+
+	respPayload, err := gw.Invoke(context.Background(), payload)
+	if err != nil {
+		t.Fatalf("Invoke failed: %v", err)
+	}
+
+	var resp events.LambdaFunctionURLResponse
+	if err := json.Unmarshal(respPayload, &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	if resp.Body != "Hello, World!" {
+		t.Errorf("expected body %q, got %q", "Hello, World!", resp.Body)
+	}
+}
@@ -6,11 +6,15 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
 	"mime"
 	"net/http"
 	"net/url"
+	"os"
+	"runtime/debug"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
@@ -24,8 +28,18 @@ import (
 // Key is the type used for any items added to the request context.
 type Key int
 
-// requestContextKey is the key for the API Gateway proxy `RequestContext`.
-const requestContextKey Key = iota
+const (
+	// requestContextKey is the key for the API Gateway proxy `RequestContext`.
+	requestContextKey Key = iota
+	// identityKey is the key for the caller Identity.
+	identityKey
+	// optionsKey is the key for the Options of the gateway.
+	optionsKey
+	// basePathKey is the key for the base path stripped from the request path.
+	basePathKey
+	// payloadKey is the key for the raw event payload.
+	payloadKey
+)
 
 // GetRequestContextKey returns the key used for storing the RequestContext in the context.
 func GetRequestContextKey() Key {
@@ -62,80 +76,242 @@ type ResponseData struct {
 	StatusCode int
 	Headers    http.Header
 	Body       []byte
+
+	// Request is the request the response was produced for. Converters use
+	// its context to shape the response after the originating event. It may be nil.
+	Request *http.Request
+}
+
+// responseContext returns the context of the request data was produced for, or
+// an empty context when there is none.
+func responseContext(data ResponseData) context.Context {
+	if data.Request == nil {
+		return context.Background()
+	}
+	return data.Request.Context()
 }
 
 // ===========================
 // Gateway Struct and Methods
 // ===========================
 
-// Gateway is a generic struct that wraps an http.Handler and converter functions
+// Gateway is a generic struct that wraps an http.Handler and converter functions.
+// It is an AutoGateway whose converters are fixed, so both behave the same.
 type Gateway[T any, R any] struct {
-	handler           http.Handler
-	requestConverter  RequestConverter[T]
-	responseConverter ResponseConverter[R]
+	auto *AutoGateway
 }
 
-// NewGateway creates a new Gateway with the given handler and converters
-func NewGateway[T any, R any](handler http.Handler, requestConverter RequestConverter[T], responseConverter ResponseConverter[R]) *Gateway[T, R] {
-	return &Gateway[T, R]{handler: handler, requestConverter: requestConverter, responseConverter: responseConverter}
+// NewGateway creates a new Gateway with the given handler, converters and options
+func NewGateway[T any, R any](handler http.Handler, requestConverter RequestConverter[T], responseConverter ResponseConverter[R], opts ...Option) *Gateway[T, R] {
+	converters := func(o *Options) {
+		o.Format = FormatOf[T]()
+		o.DecodeRequest = NewRequestDecoder(requestConverter)
+		o.EncodeResponse = NewResponseEncoder(responseConverter)
+	}
+	return &Gateway[T, R]{auto: NewAutoGateway(handler, append([]Option{converters}, opts...)...)}
 }
 
 // Invoke handles the Lambda invocation by converting the event to an HTTP request,
 // processing it, and converting the response back to the Lambda response format.
 func (gw *Gateway[T, R]) Invoke(ctx context.Context, payload []byte) ([]byte, error) {
-	var evt T
+	return gw.auto.Invoke(ctx, payload)
+}
 
-	// Unmarshal the payload into the generic event type T
-	if err := json.Unmarshal(payload, &evt); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal payload: %w", err)
-	}
+// ErrorHandler decides the response for an invocation that failed to convert.
+// Returning an error fails the invocation instead.
+type ErrorHandler func(ctx context.Context, err error) (ResponseData, error)
+
+// invoke serves a single invocation using the given codec and options. Conversion
+// errors, handler panics and handlers running into the deadline margin are passed
+// to the ErrorHandler, when set, so they can be answered with a response.
+func invoke(ctx context.Context, handler http.Handler, payload []byte, codec Codec, opts *Options) ([]byte, error) {
+	onError := opts.ErrorHandler
 
-	// Convert the event to an *http.Request using the converter function
-	req, err := gw.requestConverter(ctx, evt)
+	// Convert the payload to an *http.Request using the codec
+	req, err := codec.DecodeRequest(ctx, payload)
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert event to request: %w", err)
+		return handleError(ctx, codec, onError, nil, err)
 	}
 
 	// Create a ResponseWriter to capture the response
 	w := NewResponse()
 
 	// Serve the HTTP request using the provided handler
-	gw.handler.ServeHTTP(w, req)
+	if err := serveBeforeDeadline(handler, w, req, opts.DeadlineMargin); err != nil {
+		return handleError(ctx, codec, onError, req, err)
+	}
+
+	// Detect the Content-Type of the body unless the handler set one
+	sniffContentType(w.header, w.statusCode, w.buf.Bytes())
 
 	// Prepare the response data
 	respData := ResponseData{
 		StatusCode: w.statusCode,
 		Headers:    w.Header(),
 		Body:       w.buf.Bytes(),
+		Request:    req,
+	}
+
+	// Compress the body when the gateway is configured to
+	if opts.Compression != nil {
+		if respData, err = opts.Compression.Compress(respData); err != nil {
+			return handleError(ctx, codec, onError, req, err)
+		}
 	}
 
-	// Convert the response data to the desired response type R
-	resp, err := gw.responseConverter(respData)
+	// Convert the response data back to the event's response payload
+	out, err := encodeWithinLimit(ctx, codec, opts, respData)
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert response: %w", err)
+		return handleError(ctx, codec, onError, req, err)
+	}
+	return out, nil
+}
+
+// serveBeforeDeadline serves req with handler, giving up margin before the
+// deadline of the request context. When it gives up the request context is
+// canceled, CloseNotify is signalled and ErrHandlerTimeout is returned while the
+// handler carries on in the background.
+func serveBeforeDeadline(handler http.Handler, w *ResponseWriter, req *http.Request, margin time.Duration) error {
+	deadline, ok := req.Context().Deadline()
+	if !ok || margin <= 0 {
+		return serveHTTP(handler, w, req)
 	}
 
-	// Marshal the response back to JSON
-	return json.Marshal(resp)
+	ctx, cancel := context.WithDeadline(req.Context(), deadline.Add(-margin))
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- serveHTTP(handler, w, req.WithContext(ctx))
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		w.closeNotifyCh <- true
+		close(w.closeNotifyCh)
+		return fmt.Errorf("%w: %w", ErrHandlerTimeout, ctx.Err())
+	}
+}
+
+// serveHTTP serves req with handler, turning a panic into a *PanicError.
+func serveHTTP(handler http.Handler, w http.ResponseWriter, req *http.Request) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = &PanicError{Value: p, Stack: debug.Stack()}
+		}
+	}()
+
+	handler.ServeHTTP(w, req)
+	return nil
+}
+
+// handleError turns err into a response through onError. The original error is
+// returned when there is no handler or its response cannot be encoded either.
+func handleError(ctx context.Context, codec Codec, onError ErrorHandler, req *http.Request, err error) ([]byte, error) {
+	if onError == nil {
+		return nil, err
+	}
+
+	data, herr := onError(ctx, err)
+	if herr != nil {
+		return nil, herr
+	}
+	if data.Request == nil {
+		data.Request = req
+	}
+
+	out, eerr := codec.EncodeResponse(data)
+	if eerr != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
 // ===========================
-// ListenAndServe Function
+// Codec
 // ===========================
 
-// ListenAndServe is a generic function that sets up the Gateway and starts the Lambda handler
-func ListenAndServe[T any, R any](addr string, handler http.Handler, requestConverter RequestConverter[T], responseConverter ResponseConverter[R]) error {
-	if handler == nil {
-		handler = http.DefaultServeMux
+// RequestDecoder converts the raw payload of an event to an *http.Request.
+type RequestDecoder func(context.Context, []byte) (*http.Request, error)
+
+// ResponseEncoder converts ResponseData to the raw payload of a response.
+type ResponseEncoder func(ResponseData) ([]byte, error)
+
+// Codec converts between the raw payload of one event format and net/http.
+// It is the type-erased form of a RequestConverter and ResponseConverter pair.
+type Codec struct {
+	DecodeRequest  RequestDecoder
+	EncodeResponse ResponseEncoder
+}
+
+// NewCodec creates a Codec from a request and response converter
+func NewCodec[T any, R any](requestConverter RequestConverter[T], responseConverter ResponseConverter[R]) Codec {
+	return Codec{
+		DecodeRequest:  NewRequestDecoder(requestConverter),
+		EncodeResponse: NewResponseEncoder(responseConverter),
 	}
+}
+
+// NewRequestDecoder creates a RequestDecoder that unmarshals events of type T
+// and converts them with requestConverter
+func NewRequestDecoder[T any](requestConverter RequestConverter[T]) RequestDecoder {
+	return func(ctx context.Context, payload []byte) (*http.Request, error) {
+		var evt T
 
-	gw := NewGateway[T, R](handler, requestConverter, responseConverter)
+		// Unmarshal the payload into the generic event type T
+		if err := json.Unmarshal(payload, &evt); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrUnmarshal, err)
+		}
+
+		// Convert the event to an *http.Request using the converter function
+		req, err := requestConverter(ctx, evt)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrRequestConversion, err)
+		}
+		return req, nil
+	}
+}
+
+// NewResponseEncoder creates a ResponseEncoder that converts responses with
+// responseConverter and marshals the result
+func NewResponseEncoder[R any](responseConverter ResponseConverter[R]) ResponseEncoder {
+	return func(data ResponseData) ([]byte, error) {
+		// Convert the response data to the desired response type R
+		resp, err := responseConverter(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrResponseConversion, err)
+		}
+
+		// Marshal the response back to JSON
+		return json.Marshal(resp)
+	}
+}
+
+// ===========================
+// Serve Function
+// ===========================
+
+// Serve starts the Lambda handler gw for handler. Outside of Lambda it serves the
+// handler on addr with net/http instead, or through the Emulator using the native
+// format of gw when GATEWAY_EMULATOR is set.
+func Serve(addr string, handler http.Handler, gw lambda.Handler, native EventFormat) error {
+	if !IsLambda() {
+		return serveLocal(addr, handler, gw, native)
+	}
 
 	lambda.StartHandler(gw)
 
 	return nil
 }
 
+// IsLambda reports whether the process runs in the Lambda execution environment,
+// either through the Runtime API or the legacy go1.x RPC protocol.
+func IsLambda() bool {
+	return os.Getenv("AWS_LAMBDA_RUNTIME_API") != "" || os.Getenv("_LAMBDA_SERVER_PORT") != ""
+}
+
 // ===========================
 // Custom ResponseWriter
 // ===========================
@@ -147,6 +323,7 @@ type ResponseWriter struct {
 	wroteHeader   bool
 	statusCode    int
 	closeNotifyCh chan bool
+	writeDeadline time.Time
 }
 
 // NewResponse creates a new ResponseWriter instance.
@@ -168,22 +345,64 @@ func (w *ResponseWriter) Write(b []byte) (int, error) {
 	if !w.wroteHeader {
 		w.WriteHeader(http.StatusOK)
 	}
+	if w.pastWriteDeadline() {
+		return 0, os.ErrDeadlineExceeded
+	}
 	return w.buf.Write(b)
 }
 
+// ReadFrom copies r into the buffer, so io.Copy skips its intermediate buffer.
+func (w *ResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.pastWriteDeadline() {
+		return 0, os.ErrDeadlineExceeded
+	}
+	return w.buf.ReadFrom(r)
+}
+
 // WriteHeader sends an HTTP response header with the provided status code.
 func (w *ResponseWriter) WriteHeader(statusCode int) {
 	if w.wroteHeader {
 		return
 	}
-	if w.header.Get("Content-Type") == "" {
-		w.header.Set("Content-Type", "text/plain; charset=utf8")
-	}
 	w.statusCode = statusCode
 	w.wroteHeader = true
 }
 
-// CloseNotify notifies when the response is closed.
+// Flush sends the header like net/http does. The body is buffered until the
+// handler returns, so there is nothing else to flush.
+func (w *ResponseWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// SetWriteDeadline makes writes after t fail with os.ErrDeadlineExceeded.
+// A zero t removes the deadline.
+func (w *ResponseWriter) SetWriteDeadline(t time.Time) error {
+	w.writeDeadline = t
+	return nil
+}
+
+// SetReadDeadline does nothing, the request body is already in memory.
+func (w *ResponseWriter) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+// EnableFullDuplex does nothing, the request body can always be read while writing.
+func (w *ResponseWriter) EnableFullDuplex() error {
+	return nil
+}
+
+// pastWriteDeadline reports whether the write deadline has passed.
+func (w *ResponseWriter) pastWriteDeadline() bool {
+	return !w.writeDeadline.IsZero() && time.Now().After(w.writeDeadline)
+}
+
+// CloseNotify notifies when the response is closed, which happens when the
+// handler runs into the deadline margin of the gateway.
 func (w *ResponseWriter) CloseNotify() <-chan bool {
 	return w.closeNotifyCh
 }
@@ -192,10 +411,37 @@ func (w *ResponseWriter) CloseNotify() <-chan bool {
 // Helper Functions
 // ===========================
 
+// sniffContentType sets the Content-Type from the first 512 bytes of body like
+// net/http does when the handler set none. An empty Content-Type the handler set
+// suppresses it instead.
+func sniffContentType(h http.Header, statusCode int, body []byte) {
+	if v, ok := h["Content-Type"]; ok {
+		if len(v) == 0 || v[0] == "" {
+			delete(h, "Content-Type")
+		}
+		return
+	}
+
+	// Like net/http, encoded bodies and responses without a body are not sniffed
+	noBody := statusCode < 200 || statusCode == http.StatusNoContent || statusCode == http.StatusNotModified
+	if len(body) == 0 || noBody || h.Get("Content-Encoding") != "" {
+		return
+	}
+
+	if len(body) > 512 {
+		body = body[:512]
+	}
+	h.Set("Content-Type", http.DetectContentType(body))
+}
+
 // isBinary returns true if the response represents binary data.
 func isBinary(h http.Header) bool {
 	contentType := h.Get("Content-Type")
-	return !isTextMime(contentType) || h.Get("Content-Encoding") == "gzip"
+	if contentType == "" {
+		// A body without a type was neither sniffed as binary nor declared as such
+		return isEncoded(h)
+	}
+	return !isTextMime(contentType) || isEncoded(h)
 }
 
 // isTextMime returns true if the content type represents textual data.
@@ -205,24 +451,49 @@ func isTextMime(kind string) bool {
 		return false
 	}
 
-	if strings.HasPrefix(mt, "text/") {
+	if strings.HasPrefix(mt, "text/") || strings.HasSuffix(mt, "+json") || strings.HasSuffix(mt, "+xml") {
 		return true
 	}
 
 	switch mt {
-	case "image/svg+xml", "application/json", "application/xml", "application/javascript", "application/vnd.api+json":
+	case "application/json", "application/xml", "application/javascript", "application/x-ndjson", "application/x-www-form-urlencoded":
 		return true
 	default:
 		return false
 	}
 }
 
+// routeKeyV1 returns the V2 style route key, such as "GET /orders/{id}", of a V1 event.
+func routeKeyV1(e events.APIGatewayProxyRequest) string {
+	if e.Resource == "" {
+		return ""
+	}
+	return e.HTTPMethod + " " + e.Resource
+}
+
+// setPathValues exposes the path parameters API Gateway matched through
+// req.PathValue. Greedy {proxy+} parameters are stored without the "+".
+// REST APIs send the values escaped, so they are decoded like http.ServeMux
+// does, keeping a value that does not decode as is.
+func setPathValues(req *http.Request, params map[string]string, escaped bool) {
+	for k, v := range params {
+		if escaped {
+			if unescaped, err := url.PathUnescape(v); err == nil {
+				v = unescaped
+			}
+		}
+		req.SetPathValue(strings.TrimSuffix(k, "+"), v)
+	}
+}
+
 // ===========================
 // Response Converter Functions
 // ===========================
 
 // ConvertResponseV1 converts ResponseData to APIGatewayProxyResponse (v1)
 func ConvertResponseV1(data ResponseData) (events.APIGatewayProxyResponse, error) {
+	data, isBin := binaryBody(data)
+
 	out := events.APIGatewayProxyResponse{
 		StatusCode:        data.StatusCode,
 		Headers:           make(map[string]string),
@@ -238,8 +509,6 @@ func ConvertResponseV1(data ResponseData) (events.APIGatewayProxyResponse, error
 		}
 	}
 
-	isBin := isBinary(data.Headers)
-
 	out.IsBase64Encoded = isBin
 
 	if isBin {
@@ -253,25 +522,19 @@ func ConvertResponseV1(data ResponseData) (events.APIGatewayProxyResponse, error
 
 // ConvertResponseV2 converts ResponseData to APIGatewayV2HTTPResponse (v2)
 func ConvertResponseV2(data ResponseData) (events.APIGatewayV2HTTPResponse, error) {
-	out := events.APIGatewayV2HTTPResponse{
-		StatusCode:        data.StatusCode,
-		Headers:           make(map[string]string),
-		MultiValueHeaders: make(map[string][]string),
-		Cookies:           []string{},
-	}
+	data, isBin := binaryBody(data)
 
-	for k, v := range data.Headers {
-		if http.CanonicalHeaderKey(k) == "Set-Cookie" {
-			out.Cookies = append(out.Cookies, v...)
-		} else if len(v) == 1 {
-			out.Headers[k] = v[0]
-			out.MultiValueHeaders[k] = v
-		} else if len(v) > 1 {
-			out.MultiValueHeaders[k] = v
-		}
+	// HTTP APIs ignore multiValueHeaders, so repeated list headers are comma-joined
+	headers, cookies, err := foldHeaders(responseContext(data), data.Headers)
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
 	}
 
-	isBin := isBinary(data.Headers)
+	out := events.APIGatewayV2HTTPResponse{
+		StatusCode: data.StatusCode,
+		Headers:    headers,
+		Cookies:    cookies,
+	}
 
 	out.IsBase64Encoded = isBin
 
@@ -290,21 +553,41 @@ func ConvertResponseV2(data ResponseData) (events.APIGatewayV2HTTPResponse, erro
 
 // ConvertAPIGatewayProxyRequest converts APIGatewayProxyRequest (v1) to *http.Request
 func ConvertAPIGatewayProxyRequest(ctx context.Context, e events.APIGatewayProxyRequest) (*http.Request, error) {
-	// Parse the path
-	u, err := url.Parse(e.Path)
-	if err != nil {
-		return nil, errors.Wrap(err, "parsing path")
+	fidelity := OptionsFromContext(ctx).URLFidelity
+
+	// Parse the path, which API Gateway has already decoded
+	var u *url.URL
+	if fidelity {
+		u = &url.URL{Path: e.Path, RawPath: rawPathV1(e)}
+	} else {
+		var err error
+		if u, err = url.Parse(e.Path); err != nil {
+			return nil, errors.Wrap(err, "parsing path")
+		}
 	}
 
+	// Strip the base path chosen by the gateway options
+	ctx = stripBasePath(ctx, u, BasePath{
+		Path:           e.Path,
+		Stage:          e.RequestContext.Stage,
+		DomainName:     e.RequestContext.DomainName,
+		Resource:       e.Resource,
+		PathParameters: e.PathParameters,
+	})
+
 	// Build query parameters
-	q := u.Query()
-	for k, v := range e.QueryStringParameters {
-		q.Set(k, v)
-	}
-	for k, values := range e.MultiValueQueryStringParameters {
-		q[k] = values
+	if fidelity {
+		u.RawQuery = rawQueryV1(e, queryOrder(payloadFromContext(ctx)))
+	} else {
+		q := u.Query()
+		for k, v := range e.QueryStringParameters {
+			q.Set(k, v)
+		}
+		for k, values := range e.MultiValueQueryStringParameters {
+			q[k] = values
+		}
+		u.RawQuery = q.Encode()
 	}
-	u.RawQuery = q.Encode()
 
 	// Decode the body if it's base64 encoded
 	body := e.Body
@@ -332,7 +615,9 @@ func ConvertAPIGatewayProxyRequest(ctx context.Context, e events.APIGatewayProxy
 	for k, v := range e.Headers {
 		req.Header.Set(k, v)
 	}
+	// API Gateway sends every header in both fields, the multi-value form wins
 	for k, values := range e.MultiValueHeaders {
+		req.Header.Del(k)
 		for _, v := range values {
 			req.Header.Add(k, v)
 		}
@@ -343,12 +628,22 @@ func ConvertAPIGatewayProxyRequest(ctx context.Context, e events.APIGatewayProxy
 		req.Header.Set("Content-Length", strconv.Itoa(len(body)))
 	}
 
+	// Set the path values matched by API Gateway
+	setPathValues(req, e.PathParameters, true)
+
 	// Set custom headers
-	req.Header.Set("X-Request-Id", e.RequestContext.RequestID)
-	req.Header.Set("X-Stage", e.RequestContext.Stage)
+	injectHeaders(ctx, req, map[ContextField]string{
+		FieldRequestID:  e.RequestContext.RequestID,
+		FieldStage:      e.RequestContext.Stage,
+		FieldAPIID:      e.RequestContext.APIID,
+		FieldDomainName: e.RequestContext.DomainName,
+		FieldRouteKey:   routeKeyV1(e),
+		FieldAccountID:  e.RequestContext.AccountID,
+	})
 
 	// Add custom context values
-	req = req.WithContext(NewContext(ctx, e))
+	cert := clientCertFromPayloadV1(payloadFromContext(ctx))
+	req = req.WithContext(NewIdentityContext(NewContext(ctx, e), identityV1(e, cert)))
 
 	// X-Ray support
 	if traceID := ctx.Value("x-amzn-trace-id"); traceID != nil {
@@ -359,6 +654,9 @@ func ConvertAPIGatewayProxyRequest(ctx context.Context, e events.APIGatewayProxy
 	req.URL.Host = req.Header.Get("Host")
 	req.Host = req.URL.Host
 
+	// API Gateway only accepts HTTPS
+	setConnection(req, "https", e.RequestContext.Protocol, cert.ClientCertPem)
+
 	return req, nil
 }
 
@@ -370,6 +668,16 @@ func ConvertAPIGatewayV2HTTPRequest(ctx context.Context, e events.APIGatewayV2HT
 		return nil, errors.Wrap(err, "parsing raw path")
 	}
 
+	// Strip the base path chosen by the gateway options
+	_, route, _ := strings.Cut(e.RouteKey, " ")
+	ctx = stripBasePath(ctx, u, BasePath{
+		Path:           e.RawPath,
+		Stage:          e.RequestContext.Stage,
+		DomainName:     e.RequestContext.DomainName,
+		Resource:       route,
+		PathParameters: e.PathParameters,
+	})
+
 	// Set the raw query string
 	u.RawQuery = e.RawQueryString
 
@@ -395,10 +703,11 @@ func ConvertAPIGatewayV2HTTPRequest(ctx context.Context, e events.APIGatewayV2HT
 	// Set RemoteAddr
 	req.RemoteAddr = e.RequestContext.HTTP.SourceIP
 
-	// Set headers
+	// Set headers, splitting the values the event joined with commas
+	folding := OptionsFromContext(ctx).HeaderFolding
 	for k, values := range e.Headers {
-		for _, v := range strings.Split(values, ",") {
-			req.Header.Add(k, strings.TrimSpace(v))
+		for _, v := range folding.Split(k, values) {
+			req.Header.Add(k, v)
 		}
 	}
 	for _, c := range e.Cookies {
@@ -410,12 +719,21 @@ func ConvertAPIGatewayV2HTTPRequest(ctx context.Context, e events.APIGatewayV2HT
 		req.Header.Set("Content-Length", strconv.Itoa(len(body)))
 	}
 
+	// Set the path values matched by API Gateway
+	setPathValues(req, e.PathParameters, false)
+
 	// Set custom headers
-	req.Header.Set("X-Request-Id", e.RequestContext.RequestID)
-	req.Header.Set("X-Stage", e.RequestContext.Stage)
+	injectHeaders(ctx, req, map[ContextField]string{
+		FieldRequestID:  e.RequestContext.RequestID,
+		FieldStage:      e.RequestContext.Stage,
+		FieldAPIID:      e.RequestContext.APIID,
+		FieldDomainName: e.RequestContext.DomainName,
+		FieldRouteKey:   e.RouteKey,
+		FieldAccountID:  e.RequestContext.AccountID,
+	})
 
 	// Add custom context values
-	req = req.WithContext(NewContext(ctx, e))
+	req = req.WithContext(NewIdentityContext(NewContext(ctx, e), identityV2(e)))
 
 	// X-Ray support
 	if traceID := ctx.Value("x-amzn-trace-id"); traceID != nil {
@@ -426,5 +744,8 @@ func ConvertAPIGatewayV2HTTPRequest(ctx context.Context, e events.APIGatewayV2HT
 	req.URL.Host = req.Header.Get("Host")
 	req.Host = req.URL.Host
 
+	// API Gateway only accepts HTTPS
+	setConnection(req, "https", e.RequestContext.HTTP.Protocol, e.RequestContext.Authentication.ClientCert.ClientCertPem)
+
 	return req, nil
 }
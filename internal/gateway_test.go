@@ -4,9 +4,14 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 )
@@ -51,17 +56,26 @@ func TestNewGateway(t *testing.T) {
 		t.Fatalf("expected NewGateway to return a non-nil Gateway")
 	}
 
-	if gw.handler == nil {
+	if gw.auto.handler == nil {
 		t.Errorf("expected handler to be set")
 	}
 
-	if gw.requestConverter == nil {
+	if gw.auto.options.DecodeRequest == nil {
 		t.Errorf("expected requestConverter to be set")
 	}
 
-	if gw.responseConverter == nil {
+	if gw.auto.options.EncodeResponse == nil {
 		t.Errorf("expected responseConverter to be set")
 	}
+
+	if gw.auto.Format() != FormatAPIGatewayV1 {
+		t.Errorf("expected format %s, got %s", FormatAPIGatewayV1, gw.auto.Format())
+	}
+
+	// The defaults of an AutoGateway apply as well
+	if gw.auto.options.ErrorHandler == nil || gw.auto.options.MaxResponseSize != DefaultMaxResponseSize {
+		t.Errorf("expected the default error handler and response size limit")
+	}
 }
 
 func TestGateway_Invoke(t *testing.T) {
@@ -238,6 +252,7 @@ func TestIsBinary(t *testing.T) {
 		{http.Header{"Content-Type": []string{"application/json"}}, false},
 		{http.Header{"Content-Type": []string{"image/png"}}, true},
 		{http.Header{"Content-Encoding": []string{"gzip"}}, true},
+		{http.Header{}, false},
 	}
 
 	for _, test := range tests {
@@ -255,7 +270,14 @@ func TestIsTextMime(t *testing.T) {
 	}{
 		{"text/plain", true},
 		{"application/json", true},
+		{"application/problem+json", true},
+		{"application/ld+json; charset=utf-8", true},
+		{"application/graphql-response+json", true},
+		{"image/svg+xml", true},
+		{"application/x-ndjson", true},
+		{"application/x-www-form-urlencoded", true},
 		{"image/png", false},
+		{"application/octet-stream", false},
 	}
 
 	for _, test := range tests {
@@ -285,6 +307,123 @@ func TestResponseWriter(t *testing.T) {
 	}
 }
 
+// wrappedWriter is a middleware style writer that only exposes the writer it wraps through Unwrap.
+type wrappedWriter struct {
+	http.ResponseWriter
+}
+
+func (w wrappedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func TestResponseWriter_Controller(t *testing.T) {
+	w := NewResponse()
+
+	var _ http.Flusher = w
+	var _ io.ReaderFrom = w
+
+	// The innermost writer must not be unwrappable, or middleware walking the chain gets nil
+	if _, ok := any(w).(interface{ Unwrap() http.ResponseWriter }); ok {
+		t.Errorf("expected ResponseWriter not to implement Unwrap")
+	}
+
+	rc := http.NewResponseController(wrappedWriter{w})
+
+	if err := rc.Flush(); err != nil {
+		t.Errorf("expected Flush to be supported, got %v", err)
+	}
+
+	if !w.wroteHeader || w.statusCode != http.StatusOK {
+		t.Errorf("expected Flush to write the header, got status %d", w.statusCode)
+	}
+
+	if err := rc.EnableFullDuplex(); err != nil {
+		t.Errorf("expected EnableFullDuplex to be supported, got %v", err)
+	}
+
+	if err := rc.SetReadDeadline(time.Now()); err != nil {
+		t.Errorf("expected SetReadDeadline to be supported, got %v", err)
+	}
+
+	if _, err := io.Copy(w, strings.NewReader("copied")); err != nil {
+		t.Errorf("expected io.Copy to succeed, got %v", err)
+	}
+
+	if err := rc.SetWriteDeadline(time.Now().Add(-time.Second)); err != nil {
+		t.Errorf("expected SetWriteDeadline to be supported, got %v", err)
+	}
+
+	if _, err := w.Write([]byte("late")); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("expected os.ErrDeadlineExceeded after the write deadline, got %v", err)
+	}
+
+	if w.buf.String() != "copied" {
+		t.Errorf("expected body %q, got %q", "copied", w.buf.String())
+	}
+
+	if _, _, err := rc.Hijack(); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("expected Hijack to be unsupported, got %v", err)
+	}
+}
+
+func TestSniffContentType(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+	tests := []struct {
+		name     string
+		header   http.Header
+		status   int
+		body     []byte
+		expected []string
+	}{
+		{"png", http.Header{}, http.StatusOK, png, []string{"image/png"}},
+		{"html", http.Header{}, http.StatusOK, []byte("<html><body>hi</body></html>"), []string{"text/html; charset=utf-8"}},
+		{"plain text", http.Header{}, http.StatusOK, []byte("hello"), []string{"text/plain; charset=utf-8"}},
+		{"set by handler", http.Header{"Content-Type": {"application/json"}}, http.StatusOK, png, []string{"application/json"}},
+		{"suppressed with nil", http.Header{"Content-Type": nil}, http.StatusOK, png, nil},
+		{"suppressed with empty", http.Header{"Content-Type": {""}}, http.StatusOK, png, nil},
+		{"empty body", http.Header{}, http.StatusOK, nil, nil},
+		{"no content", http.Header{}, http.StatusNoContent, []byte("x"), nil},
+		{"encoded", http.Header{"Content-Encoding": {"gzip"}}, http.StatusOK, png, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sniffContentType(tt.header, tt.status, tt.body)
+			if got := tt.header["Content-Type"]; !equalStringSlices(got, tt.expected) {
+				t.Errorf("expected Content-Type %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestGateway_InvokeSniffsBinary(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(png)
+	})
+
+	gw := NewGateway(handler, ConvertAPIGatewayProxyRequest, ConvertResponseV1)
+
+	out, err := gw.Invoke(context.Background(), []byte(`{"httpMethod":"GET","path":"/"}`))
+	if err != nil {
+		t.Fatalf("Invoke failed: %v", err)
+	}
+
+	var resp events.APIGatewayProxyResponse
+	if err := json.Unmarshal(out, &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if resp.Headers["Content-Type"] != "image/png" {
+		t.Errorf("expected Content-Type image/png, got %q", resp.Headers["Content-Type"])
+	}
+
+	if !resp.IsBase64Encoded || resp.Body != base64.StdEncoding.EncodeToString(png) {
+		t.Errorf("expected the PNG to be base64 encoded intact, got %q", resp.Body)
+	}
+}
+
 func TestConvertAPIGatewayProxyRequest_MultiHeaders(t *testing.T) {
 	event := events.APIGatewayProxyRequest{
 		HTTPMethod: "GET",
@@ -321,6 +460,34 @@ func TestConvertAPIGatewayProxyRequest_MultiHeaders(t *testing.T) {
 	}
 }
 
+func TestConvertAPIGatewayProxyRequest_HeadersInBothFields(t *testing.T) {
+	event := events.APIGatewayProxyRequest{
+		HTTPMethod: "GET",
+		Path:       "/test",
+		Headers: map[string]string{
+			"Accept":          "*/*",
+			"X-Custom-Header": "value2",
+		},
+		MultiValueHeaders: map[string][]string{
+			"Accept":          {"*/*"},
+			"X-Custom-Header": {"value1", "value2"},
+		},
+	}
+
+	req, err := ConvertAPIGatewayProxyRequest(context.Background(), event)
+	if err != nil {
+		t.Fatalf("ConvertAPIGatewayProxyRequest failed: %v", err)
+	}
+
+	if !equalStringSlices(req.Header["Accept"], []string{"*/*"}) {
+		t.Errorf("expected Accept [*/*], got %v", req.Header["Accept"])
+	}
+
+	if !equalStringSlices(req.Header["X-Custom-Header"], []string{"value1", "value2"}) {
+		t.Errorf("expected X-Custom-Header [value1, value2], got %v", req.Header["X-Custom-Header"])
+	}
+}
+
 func TestConvertResponseV2(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -372,16 +539,29 @@ func TestConvertResponseV2(t *testing.T) {
 			data: ResponseData{
 				StatusCode: http.StatusOK,
 				Headers:    http.Header{"Set-Cookie": []string{"cookie1=value1", "cookie2=value2"}},
-				Body:       []byte("Hello, World!"),
+				Body:       []byte{0x89, 0x50, 0x4E, 0x47},
 			},
 			expected: events.APIGatewayV2HTTPResponse{
 				StatusCode:      http.StatusOK,
 				Headers:         map[string]string{},
 				Cookies:         []string{"cookie1=value1", "cookie2=value2"},
-				Body:            base64.StdEncoding.EncodeToString([]byte("Hello, World!")),
+				Body:            base64.StdEncoding.EncodeToString([]byte{0x89, 0x50, 0x4E, 0x47}),
 				IsBase64Encoded: true,
 			},
 		},
+		{
+			name: "untyped text response",
+			data: ResponseData{
+				StatusCode: http.StatusOK,
+				Headers:    http.Header{},
+				Body:       []byte("Hello, World!"),
+			},
+			expected: events.APIGatewayV2HTTPResponse{
+				StatusCode: http.StatusOK,
+				Headers:    map[string]string{},
+				Body:       "Hello, World!",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -703,55 +883,71 @@ func TestConvertAPIGatewayV2HTTPRequest_TraceID(t *testing.T) {
 func TestConvertResponseV2_MultiValueHeaders(t *testing.T) {
 	tests := []struct {
 		name     string
-		data     ResponseData
-		expected events.APIGatewayV2HTTPResponse
+		folding  HeaderFolding
+		headers  http.Header
+		expected map[string]string
+		wantErr  bool
 	}{
 		{
-			name: "multi-value headers",
-			data: ResponseData{
-				StatusCode: http.StatusOK,
-				Headers: http.Header{
-					"Content-Type":    []string{"application/json"},
-					"X-Custom-Header": []string{"value1", "value2"},
-				},
-				Body: []byte(`{"message":"Hello, World!"}`),
+			name: "list headers are comma-joined",
+			headers: http.Header{
+				"Vary":             {"Accept", "Origin"},
+				"Link":             {`</a>; rel="preload"`, `</b>; rel="next"`},
+				"Www-Authenticate": {`Basic realm="api"`, `Bearer realm="api", scope="read"`},
 			},
-			expected: events.APIGatewayV2HTTPResponse{
-				StatusCode: http.StatusOK,
-				Headers:    map[string]string{"Content-Type": "application/json"},
-				MultiValueHeaders: map[string][]string{
-					"X-Custom-Header": {"value1", "value2"},
-				},
-				Body: `{"message":"Hello, World!"}`,
+			expected: map[string]string{
+				"Vary":             "Accept, Origin",
+				"Link":             `</a>; rel="preload", </b>; rel="next"`,
+				"Www-Authenticate": `Basic realm="api", Bearer realm="api", scope="read"`,
 			},
 		},
+		{
+			name:     "other headers send their first value",
+			headers:  http.Header{"X-Custom-Header": {"value1", "value2"}},
+			expected: map[string]string{"X-Custom-Header": "value1"},
+		},
+		{
+			name:     "registered list headers are comma-joined",
+			folding:  HeaderFolding{List: []string{"x-custom-header"}},
+			headers:  http.Header{"X-Custom-Header": {"value1", "value2"}},
+			expected: map[string]string{"X-Custom-Header": "value1, value2"},
+		},
+		{
+			name:    "strict folding fails",
+			folding: HeaderFolding{Strict: true},
+			headers: http.Header{"Content-Type": {"text/plain", "text/html"}},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			resp, err := ConvertResponseV2(tt.data)
-			if err != nil {
-				t.Fatalf("ConvertResponseV2 failed: %v", err)
+			ctx := NewOptionsContext(context.Background(), &Options{HeaderFolding: tt.folding})
+			data := ResponseData{
+				StatusCode: http.StatusOK,
+				Headers:    tt.headers,
+				Request:    httptest.NewRequest("GET", "/", nil).WithContext(ctx),
 			}
 
-			if resp.StatusCode != tt.expected.StatusCode {
-				t.Errorf("expected status code %d, got %d", tt.expected.StatusCode, resp.StatusCode)
+			resp, err := ConvertResponseV2(data)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnfoldableHeader) {
+					t.Errorf("expected ErrUnfoldableHeader, got %v", err)
+				}
+				return
 			}
-
-			if resp.Body != tt.expected.Body {
-				t.Errorf("expected body %q, got %q", tt.expected.Body, resp.Body)
+			if err != nil {
+				t.Fatalf("ConvertResponseV2 failed: %v", err)
 			}
 
-			for k, v := range tt.expected.Headers {
+			for k, v := range tt.expected {
 				if resp.Headers[k] != v {
 					t.Errorf("expected header %s to be %q, got %q", k, v, resp.Headers[k])
 				}
 			}
 
-			for k, v := range tt.expected.MultiValueHeaders {
-				if !equalStringSlices(resp.MultiValueHeaders[k], v) {
-					t.Errorf("expected multi-value header %s to be %v, got %v", k, v, resp.MultiValueHeaders[k])
-				}
+			if resp.MultiValueHeaders != nil {
+				t.Errorf("expected no multi-value headers, got %v", resp.MultiValueHeaders)
 			}
 		})
 	}
@@ -768,3 +964,72 @@ func equalStringSlices(a, b []string) bool {
 	}
 	return true
 }
+
+func TestConvertRequest_PathValues(t *testing.T) {
+	v1, err := ConvertAPIGatewayProxyRequest(context.Background(), events.APIGatewayProxyRequest{
+		HTTPMethod:     "GET",
+		Resource:       "/users/{id}/files/{proxy+}",
+		Path:           "/users/42/files/a/b c.txt",
+		PathParameters: map[string]string{"id": "42", "proxy": "a%2Fb%20c.txt"},
+	})
+	if err != nil {
+		t.Fatalf("ConvertAPIGatewayProxyRequest failed: %v", err)
+	}
+
+	v2, err := ConvertAPIGatewayV2HTTPRequest(context.Background(), events.APIGatewayV2HTTPRequest{
+		RawPath:        "/users/42/files/a/b%20c.txt",
+		PathParameters: map[string]string{"id": "42", "proxy": "a/b c.txt"},
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ConvertAPIGatewayV2HTTPRequest failed: %v", err)
+	}
+
+	for _, req := range []*http.Request{v1, v2} {
+		if req.PathValue("id") != "42" {
+			t.Errorf("expected path value id 42, got %q", req.PathValue("id"))
+		}
+		if req.PathValue("proxy") != "a/b c.txt" {
+			t.Errorf("expected greedy path value proxy %q, got %q", "a/b c.txt", req.PathValue("proxy"))
+		}
+	}
+
+	// A ServeMux matches the same request with its own patterns
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}/files/{proxy...}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.PathValue("id") + " " + r.PathValue("proxy")))
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, v1)
+	if w.Body.String() != "42 a/b c.txt" {
+		t.Errorf("expected the mux to match %q, got %q", "42 a/b c.txt", w.Body.String())
+	}
+}
+
+func TestIsLambda(t *testing.T) {
+	t.Setenv("AWS_LAMBDA_RUNTIME_API", "")
+	t.Setenv("_LAMBDA_SERVER_PORT", "")
+	if IsLambda() {
+		t.Errorf("expected IsLambda to be false without the Lambda environment")
+	}
+
+	t.Setenv("AWS_LAMBDA_RUNTIME_API", "127.0.0.1:9001")
+	if !IsLambda() {
+		t.Errorf("expected IsLambda to be true when AWS_LAMBDA_RUNTIME_API is set")
+	}
+}
+
+func TestListenAndServe_OutsideLambda(t *testing.T) {
+	t.Setenv("AWS_LAMBDA_RUNTIME_API", "")
+	t.Setenv("_LAMBDA_SERVER_PORT", "")
+
+	// An unusable address proves the handler is served with net/http
+	handler := http.NotFoundHandler()
+	err := Serve("invalid-address", handler, NewAutoGateway(handler), FormatUnknown)
+	if err == nil {
+		t.Fatalf("expected an error from net/http for an invalid address")
+	}
+}
@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+)
+
+// ===========================
+// Injected Headers
+// ===========================
+
+// ContextField names a request context field that can be copied into a request header.
+type ContextField int
+
+const (
+	FieldRequestID ContextField = iota
+	FieldStage
+	FieldAPIID
+	FieldDomainName
+	FieldRouteKey
+	FieldAccountID
+)
+
+// HeaderPolicy decides which request context fields the converters copy into
+// request headers. An empty Headers map turns injection off.
+type HeaderPolicy struct {
+	// Headers maps a request context field to the name of its header.
+	Headers map[ContextField]string
+	// Keep leaves a header the client already sent alone instead of overwriting it.
+	Keep bool
+}
+
+// defaultHeaderPolicy is used when the gateway has no HeaderPolicy.
+var defaultHeaderPolicy = &HeaderPolicy{
+	Headers: map[ContextField]string{
+		FieldRequestID: "X-Request-Id",
+		FieldStage:     "X-Stage",
+	},
+}
+
+// injectHeaders copies the non-empty fields into req as the HeaderPolicy of the gateway asks.
+func injectHeaders(ctx context.Context, req *http.Request, fields map[ContextField]string) {
+	policy := OptionsFromContext(ctx).Headers
+	if policy == nil {
+		policy = defaultHeaderPolicy
+	}
+
+	for field, name := range policy.Headers {
+		v := fields[field]
+		if v == "" || (policy.Keep && req.Header.Get(name) != "") {
+			continue
+		}
+		req.Header.Set(name, v)
+	}
+}
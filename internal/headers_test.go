@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestInjectHeaders(t *testing.T) {
+	e := events.APIGatewayProxyRequest{
+		HTTPMethod: "GET",
+		Path:       "/orders/42",
+		Resource:   "/orders/{id}",
+		Headers:    map[string]string{"X-Request-Id": "from-client"},
+		RequestContext: events.APIGatewayProxyRequestContext{
+			RequestID: "req-1",
+			APIID:     "abc123",
+		},
+	}
+
+	tests := []struct {
+		name     string
+		policy   *HeaderPolicy
+		expected map[string]string
+	}{
+		{
+			name:     "default",
+			policy:   nil,
+			expected: map[string]string{"X-Request-Id": "req-1", "X-Stage": ""},
+		},
+		{
+			name: "keep and map extra fields",
+			policy: &HeaderPolicy{
+				Headers: map[ContextField]string{FieldRequestID: "X-Request-Id", FieldAPIID: "X-Api-Id", FieldRouteKey: "X-Route"},
+				Keep:    true,
+			},
+			expected: map[string]string{"X-Request-Id": "from-client", "X-Api-Id": "abc123", "X-Route": "GET /orders/{id}"},
+		},
+		{
+			name:     "disabled",
+			policy:   &HeaderPolicy{},
+			expected: map[string]string{"X-Request-Id": "from-client", "X-Stage": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewOptionsContext(context.Background(), &Options{Headers: tt.policy})
+
+			req, err := ConvertAPIGatewayProxyRequest(ctx, e)
+			if err != nil {
+				t.Fatalf("ConvertAPIGatewayProxyRequest failed: %v", err)
+			}
+
+			for name, v := range tt.expected {
+				if got := req.Header.Get(name); got != v {
+					t.Errorf("expected %s %q, got %q", name, v, got)
+				}
+			}
+
+			// An empty stage never produces a header
+			if _, ok := req.Header["X-Stage"]; ok {
+				t.Errorf("expected no X-Stage header for an empty stage")
+			}
+		})
+	}
+}
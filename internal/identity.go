@@ -0,0 +1,141 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ===========================
+// Caller Identity
+// ===========================
+
+// Identity describes the caller of a request the same way for every event format.
+// Fields the event format does not carry are left empty.
+type Identity struct {
+	SourceIP  string
+	UserAgent string
+
+	// Claims holds the claims of a JWT or Cognito user pool authorizer.
+	Claims map[string]string
+
+	// UserARN and AccountID identify callers signed with IAM.
+	UserARN   string
+	AccountID string
+
+	// CognitoIdentityID and CognitoIdentityPoolID identify Cognito identity pool callers.
+	CognitoIdentityID     string
+	CognitoIdentityPoolID string
+
+	// APIKey is the API key of a REST API usage plan.
+	APIKey string
+
+	// ClientCertSubject is the subject DN of the mutual TLS client certificate.
+	ClientCertSubject string
+}
+
+// NewIdentityContext returns a new Context carrying the caller Identity.
+func NewIdentityContext(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, identityKey, id)
+}
+
+// IdentityFromContext retrieves the caller Identity from the context.
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(identityKey).(Identity)
+	return id, ok
+}
+
+// clientCertV1 is the mutual TLS client certificate of a V1 event.
+type clientCertV1 struct {
+	ClientCertPem string `json:"clientCertPem"`
+	SubjectDN     string `json:"subjectDN"`
+}
+
+// clientCertFromPayloadV1 reads the mutual TLS client certificate of a raw V1
+// payload, which events.APIGatewayRequestIdentity has no field for.
+func clientCertFromPayloadV1(payload []byte) clientCertV1 {
+	var e struct {
+		RequestContext struct {
+			Identity struct {
+				ClientCert clientCertV1 `json:"clientCert"`
+			} `json:"identity"`
+		} `json:"requestContext"`
+	}
+	if bytes.Contains(payload, []byte(`"clientCert"`)) {
+		json.Unmarshal(payload, &e)
+	}
+	return e.RequestContext.Identity.ClientCert
+}
+
+// identityV1 reads the caller of an API Gateway V1 event.
+func identityV1(e events.APIGatewayProxyRequest, cert clientCertV1) Identity {
+	src := e.RequestContext.Identity
+	id := Identity{
+		SourceIP:              src.SourceIP,
+		UserAgent:             src.UserAgent,
+		UserARN:               src.UserArn,
+		AccountID:             src.AccountID,
+		CognitoIdentityID:     src.CognitoIdentityID,
+		CognitoIdentityPoolID: src.CognitoIdentityPoolID,
+		APIKey:                src.APIKey,
+		ClientCertSubject:     cert.SubjectDN,
+	}
+
+	// Cognito user pool authorizers put the token claims under "claims"
+	if claims, ok := e.RequestContext.Authorizer["claims"].(map[string]interface{}); ok {
+		id.Claims = make(map[string]string, len(claims))
+		for k, v := range claims {
+			id.Claims[k] = fmt.Sprint(v)
+		}
+	}
+	return id
+}
+
+// identityV2 reads the caller of an API Gateway V2 event.
+func identityV2(e events.APIGatewayV2HTTPRequest) Identity {
+	id := Identity{
+		SourceIP:          e.RequestContext.HTTP.SourceIP,
+		UserAgent:         e.RequestContext.HTTP.UserAgent,
+		ClientCertSubject: e.RequestContext.Authentication.ClientCert.SubjectDN,
+	}
+
+	if auth := e.RequestContext.Authorizer; auth != nil {
+		if auth.JWT != nil {
+			id.Claims = auth.JWT.Claims
+		}
+		if auth.IAM != nil {
+			id.UserARN = auth.IAM.UserARN
+			id.AccountID = auth.IAM.AccountID
+			id.CognitoIdentityID = auth.IAM.CognitoIdentity.IdentityID
+			id.CognitoIdentityPoolID = auth.IAM.CognitoIdentity.IdentityPoolID
+		}
+	}
+	return id
+}
+
+// identityFunctionURL reads the caller of a Function URL event.
+func identityFunctionURL(e events.LambdaFunctionURLRequest) Identity {
+	id := Identity{
+		SourceIP:  e.RequestContext.HTTP.SourceIP,
+		UserAgent: e.RequestContext.HTTP.UserAgent,
+	}
+
+	if auth := e.RequestContext.Authorizer; auth != nil && auth.IAM != nil {
+		id.UserARN = auth.IAM.UserARN
+		id.AccountID = auth.IAM.AccountID
+	}
+	return id
+}
+
+// identityALB reads the caller of an ALB request, which only carries it in headers.
+// The source IP is the X-Forwarded-For entry ALB appended, not one the client sent.
+func identityALB(req *http.Request) Identity {
+	return Identity{
+		SourceIP:  albClientIP(req.Header),
+		UserAgent: req.UserAgent(),
+	}
+}
@@ -0,0 +1,163 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestIdentity(t *testing.T) {
+	tests := []struct {
+		name     string
+		convert  func() (*http.Request, error)
+		expected Identity
+	}{
+		{
+			name: "api gateway v1",
+			convert: func() (*http.Request, error) {
+				return ConvertAPIGatewayProxyRequest(context.Background(), events.APIGatewayProxyRequest{
+					HTTPMethod: "GET",
+					Path:       "/",
+					RequestContext: events.APIGatewayProxyRequestContext{
+						Identity: events.APIGatewayRequestIdentity{
+							SourceIP:          "203.0.113.1",
+							UserAgent:         "curl/8.0",
+							UserArn:           "arn:aws:iam::123456789012:user/gopher",
+							AccountID:         "123456789012",
+							CognitoIdentityID: "us-east-1:abc",
+							APIKey:            "key-1",
+						},
+						Authorizer: map[string]interface{}{"claims": map[string]interface{}{"sub": "user-1"}},
+					},
+				})
+			},
+			expected: Identity{
+				SourceIP:          "203.0.113.1",
+				UserAgent:         "curl/8.0",
+				Claims:            map[string]string{"sub": "user-1"},
+				UserARN:           "arn:aws:iam::123456789012:user/gopher",
+				AccountID:         "123456789012",
+				CognitoIdentityID: "us-east-1:abc",
+				APIKey:            "key-1",
+			},
+		},
+		{
+			name: "api gateway v1 mutual tls",
+			convert: func() (*http.Request, error) {
+				payload := []byte(`{"httpMethod":"GET","path":"/","requestContext":{"identity":{"sourceIp":"203.0.113.1",` +
+					`"clientCert":{"clientCertPem":"-----BEGIN CERTIFICATE-----","subjectDN":"CN=client"}}}}`)
+				var e events.APIGatewayProxyRequest
+				if err := json.Unmarshal(payload, &e); err != nil {
+					return nil, err
+				}
+				return ConvertAPIGatewayProxyRequest(context.WithValue(context.Background(), payloadKey, payload), e)
+			},
+			expected: Identity{
+				SourceIP:          "203.0.113.1",
+				ClientCertSubject: "CN=client",
+			},
+		},
+		{
+			name: "api gateway v2",
+			convert: func() (*http.Request, error) {
+				return ConvertAPIGatewayV2HTTPRequest(context.Background(), events.APIGatewayV2HTTPRequest{
+					RawPath: "/",
+					RequestContext: events.APIGatewayV2HTTPRequestContext{
+						HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET", SourceIP: "203.0.113.1", UserAgent: "curl/8.0"},
+						Authorizer: &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+							JWT: &events.APIGatewayV2HTTPRequestContextAuthorizerJWTDescription{Claims: map[string]string{"sub": "user-1"}},
+						},
+						Authentication: events.APIGatewayV2HTTPRequestContextAuthentication{
+							ClientCert: events.APIGatewayV2HTTPRequestContextAuthenticationClientCert{SubjectDN: "CN=client"},
+						},
+					},
+				})
+			},
+			expected: Identity{
+				SourceIP:          "203.0.113.1",
+				UserAgent:         "curl/8.0",
+				Claims:            map[string]string{"sub": "user-1"},
+				ClientCertSubject: "CN=client",
+			},
+		},
+		{
+			name: "alb",
+			convert: func() (*http.Request, error) {
+				return ConvertALBTargetGroupRequest(context.Background(), events.ALBTargetGroupRequest{
+					HTTPMethod: "GET",
+					Path:       "/",
+					Headers:    map[string]string{"x-forwarded-for": "10.0.0.1, 203.0.113.1", "user-agent": "curl/8.0"},
+				})
+			},
+			expected: Identity{SourceIP: "203.0.113.1", UserAgent: "curl/8.0"},
+		},
+		{
+			name: "alb spoofed forwarded for",
+			convert: func() (*http.Request, error) {
+				return ConvertALBTargetGroupRequest(context.Background(), events.ALBTargetGroupRequest{
+					HTTPMethod: "GET",
+					Path:       "/",
+					Headers:    map[string]string{"x-forwarded-for": "1.2.3.4, 9.9.9.9"},
+				})
+			},
+			expected: Identity{SourceIP: "9.9.9.9"},
+		},
+		{
+			name: "function url",
+			convert: func() (*http.Request, error) {
+				return ConvertLambdaFunctionURLRequest(context.Background(), events.LambdaFunctionURLRequest{
+					RawPath: "/",
+					RequestContext: events.LambdaFunctionURLRequestContext{
+						HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: "GET", SourceIP: "203.0.113.1", UserAgent: "curl/8.0"},
+						Authorizer: &events.LambdaFunctionURLRequestContextAuthorizerDescription{
+							IAM: &events.LambdaFunctionURLRequestContextAuthorizerIAMDescription{UserARN: "arn:aws:iam::123456789012:user/gopher", AccountID: "123456789012"},
+						},
+					},
+				})
+			},
+			expected: Identity{
+				SourceIP:  "203.0.113.1",
+				UserAgent: "curl/8.0",
+				UserARN:   "arn:aws:iam::123456789012:user/gopher",
+				AccountID: "123456789012",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := tt.convert()
+			if err != nil {
+				t.Fatalf("conversion failed: %v", err)
+			}
+
+			id, ok := IdentityFromContext(req.Context())
+			if !ok {
+				t.Fatalf("expected an identity in the request context")
+			}
+
+			if id.SourceIP != tt.expected.SourceIP || id.UserAgent != tt.expected.UserAgent {
+				t.Errorf("expected source %s / %s, got %s / %s", tt.expected.SourceIP, tt.expected.UserAgent, id.SourceIP, id.UserAgent)
+			}
+
+			if id.UserARN != tt.expected.UserARN || id.AccountID != tt.expected.AccountID {
+				t.Errorf("expected IAM caller %s / %s, got %s / %s", tt.expected.UserARN, tt.expected.AccountID, id.UserARN, id.AccountID)
+			}
+
+			if id.CognitoIdentityID != tt.expected.CognitoIdentityID || id.APIKey != tt.expected.APIKey {
+				t.Errorf("expected Cognito identity %q and API key %q, got %q and %q", tt.expected.CognitoIdentityID, tt.expected.APIKey, id.CognitoIdentityID, id.APIKey)
+			}
+
+			if id.ClientCertSubject != tt.expected.ClientCertSubject {
+				t.Errorf("expected client cert subject %q, got %q", tt.expected.ClientCertSubject, id.ClientCertSubject)
+			}
+
+			if id.Claims["sub"] != tt.expected.Claims["sub"] {
+				t.Errorf("expected claims %v, got %v", tt.expected.Claims, id.Claims)
+			}
+		})
+	}
+}
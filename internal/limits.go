@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// ===========================
+// Payload Size Limits
+// ===========================
+
+// DefaultMaxResponseSize is the 6 MB limit Lambda puts on the response payload
+// of a synchronous invocation.
+const DefaultMaxResponseSize = 6 * 1024 * 1024
+
+// OversizePolicy replaces a response whose encoded payload of size bytes is over
+// the size limit. Returning an error passes it to the ErrorHandler.
+type OversizePolicy func(ctx context.Context, data ResponseData, size int) (ResponseData, error)
+
+// RejectOversized replaces the response with an application/problem+json
+// response with the given status, such as 413 or 502.
+func RejectOversized(status int) OversizePolicy {
+	return func(ctx context.Context, data ResponseData, size int) (ResponseData, error) {
+		limit := OptionsFromContext(ctx).MaxResponseSize
+		problem, err := json.Marshal(map[string]any{
+			"type":   "about:blank",
+			"title":  http.StatusText(status),
+			"status": status,
+			"detail": fmt.Sprintf("response payload of %d bytes is over the limit of %d bytes", size, limit),
+		})
+		if err != nil {
+			return ResponseData{}, err
+		}
+
+		return ResponseData{
+			StatusCode: status,
+			Headers:    http.Header{"Content-Type": {"application/problem+json"}},
+			Body:       problem,
+			Request:    data.Request,
+		}, nil
+	}
+}
+
+// CompressOversized gzips the body when the client accepts it and leaves the
+// response to fallback when it cannot be compressed or is still too large.
+func CompressOversized(fallback OversizePolicy) OversizePolicy {
+	return func(ctx context.Context, data ResponseData, size int) (ResponseData, error) {
+		if data.Request == nil || negotiateEncoding(data.Request, []string{"gzip"}) == "" || data.Headers.Get("Content-Encoding") != "" {
+			return fallback(ctx, data, size)
+		}
+
+		compressed, err := compressResponse(data, "gzip", (&Compression{}).newWriter)
+		if err != nil {
+			return ResponseData{}, err
+		}
+
+		// base64 makes the compressed body a third larger again
+		if limit := OptionsFromContext(ctx).MaxResponseSize; len(compressed.Body)*4/3 > limit {
+			return fallback(ctx, data, size)
+		}
+		return compressed, nil
+	}
+}
+
+// encodeWithinLimit encodes data, handing it to the OversizePolicy of the
+// gateway when the payload is over the size limit.
+func encodeWithinLimit(ctx context.Context, codec Codec, opts *Options, data ResponseData) ([]byte, error) {
+	out, err := codec.EncodeResponse(data)
+	if err != nil || opts.MaxResponseSize <= 0 || len(out) <= opts.MaxResponseSize {
+		return out, err
+	}
+
+	policy := opts.OversizePolicy
+	if policy == nil {
+		policy = CompressOversized(RejectOversized(http.StatusBadGateway))
+	}
+
+	replaced, err := policy(ctx, data, len(out))
+	if err != nil {
+		return nil, err
+	}
+
+	out, err = codec.EncodeResponse(replaced)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > opts.MaxResponseSize {
+		return nil, fmt.Errorf("%w: %d bytes over the limit of %d", ErrResponseTooLarge, len(out), opts.MaxResponseSize)
+	}
+	return out, nil
+}
@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestAutoGateway_MaxResponseSize(t *testing.T) {
+	body := strings.Repeat("a", 4096)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(body))
+	})
+
+	var hooked int
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		policy         OversizePolicy
+		status         int
+		contentType    string
+	}{
+		{"default compresses", "gzip, deflate", nil, http.StatusOK, "text/plain"},
+		{"default rejects without gzip", "", nil, http.StatusBadGateway, "application/problem+json"},
+		{"reject with 413", "gzip", RejectOversized(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge, "application/problem+json"},
+		{
+			name:           "hook",
+			acceptEncoding: "gzip",
+			policy: func(ctx context.Context, data ResponseData, size int) (ResponseData, error) {
+				hooked = size
+				return ResponseData{StatusCode: http.StatusSeeOther, Headers: http.Header{"Location": {"/large"}}}, nil
+			},
+			status: http.StatusSeeOther,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gw := NewAutoGateway(handler, func(o *Options) {
+				o.MaxResponseSize = 2048
+				o.OversizePolicy = tt.policy
+			})
+
+			var e events.APIGatewayV2HTTPRequest
+			if err := json.Unmarshal([]byte(testEventV2), &e); err != nil {
+				t.Fatalf("failed to unmarshal event: %v", err)
+			}
+			e.Headers["accept-encoding"] = tt.acceptEncoding
+			payload, _ := json.Marshal(e)
+
+			out, err := gw.Invoke(context.Background(), payload)
+			if err != nil {
+				t.Fatalf("Invoke failed: %v", err)
+			}
+
+			if len(out) > 2048 {
+				t.Errorf("expected a payload within the limit, got %d bytes", len(out))
+			}
+
+			var resp events.APIGatewayV2HTTPResponse
+			if err := json.Unmarshal(out, &resp); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("expected status code %d, got %d", tt.status, resp.StatusCode)
+			}
+
+			if tt.contentType != "" && resp.Headers["Content-Type"] != tt.contentType {
+				t.Errorf("expected Content-Type %s, got %s", tt.contentType, resp.Headers["Content-Type"])
+			}
+
+			if tt.status == http.StatusOK {
+				compressed, _ := base64.StdEncoding.DecodeString(resp.Body)
+				zr, err := gzip.NewReader(bytes.NewReader(compressed))
+				if err != nil {
+					t.Fatalf("expected a gzip body: %v", err)
+				}
+				plain, _ := io.ReadAll(zr)
+				if string(plain) != body {
+					t.Errorf("expected the compressed body to round-trip")
+				}
+			}
+		})
+	}
+
+	if hooked <= 2048 {
+		t.Errorf("expected the hook to get the oversized payload size, got %d", hooked)
+	}
+}
@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultDeadlineMargin is how long before the Lambda deadline a gateway gives
+// up on the handler when no DeadlineMargin is set.
+const DefaultDeadlineMargin = 500 * time.Millisecond
+
+// ===========================
+// Options
+// ===========================
+
+// Options customizes an AutoGateway.
+type Options struct {
+	// Format is the event format of the custom converters, if known. The
+	// Emulator serves it instead of the GATEWAY_EMULATOR setting.
+	Format EventFormat
+	// DecodeRequest replaces the request conversion of the detected format.
+	DecodeRequest RequestDecoder
+	// EncodeResponse replaces the response conversion of the detected format.
+	EncodeResponse ResponseEncoder
+	// ErrorHandler answers conversion errors, handler panics, timeouts and
+	// oversized responses. When nil DefaultErrorHandler is used.
+	ErrorHandler ErrorHandler
+	// BasePath decides which prefix of the event path is stripped before routing.
+	BasePath BasePathPolicy
+	// Headers decides which request context fields are copied into request
+	// headers. When nil the request ID and stage are set as X-Request-Id and X-Stage.
+	Headers *HeaderPolicy
+	// DeadlineMargin is how long before the Lambda deadline the request context
+	// is canceled and the invocation answered with ErrHandlerTimeout. Zero uses
+	// DefaultDeadlineMargin and a negative margin waits for the handler.
+	DeadlineMargin time.Duration
+	// MaxResponseSize is the budget for the encoded response payload, including
+	// base64 and headers. Zero uses DefaultMaxResponseSize and a negative size
+	// turns the check off.
+	MaxResponseSize int
+	// OversizePolicy replaces responses over MaxResponseSize. When nil the body is
+	// compressed if the client accepts gzip, and a 502 problem response sent otherwise.
+	OversizePolicy OversizePolicy
+	// Compression compresses response bodies when set.
+	Compression *Compression
+	// Binary decides which response bodies are base64 encoded. When nil textual
+	// types, including +json and +xml ones, are sent as is.
+	Binary BinaryPolicy
+	// HeaderFolding adds headers to split into, or keep out of, comma-separated lists.
+	HeaderFolding HeaderFolding
+	// URLFidelity rebuilds the escaped path and the query string of V1 events
+	// in their original order and escaping, as far as the event allows.
+	URLFidelity bool
+}
+
+// Option sets a field of Options.
+type Option func(*Options)
+
+// NewOptionsContext returns a new Context carrying the options of the gateway,
+// so the converters can read them.
+func NewOptionsContext(ctx context.Context, opts *Options) context.Context {
+	return context.WithValue(ctx, optionsKey, opts)
+}
+
+// OptionsFromContext returns the options of the gateway serving ctx, or the
+// zero Options when there are none.
+func OptionsFromContext(ctx context.Context) *Options {
+	if opts, ok := ctx.Value(optionsKey).(*Options); ok {
+		return opts
+	}
+	return &Options{}
+}
@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ===========================
+// V1 URL Fidelity
+// ===========================
+
+// payloadFromContext returns the raw event payload of the invocation, if any.
+func payloadFromContext(ctx context.Context) []byte {
+	payload, _ := ctx.Value(payloadKey).([]byte)
+	return payload
+}
+
+// rawPathV1 returns the escaped form of the decoded e.Path, so that segments
+// such as a%2Fb survive, when the path of the request context or the path
+// parameters carry it. A candidate is only used if it decodes to e.Path.
+func rawPathV1(e events.APIGatewayProxyRequest) string {
+	for _, candidate := range []string{e.RequestContext.Path, expandResource(e.Resource, e.PathParameters)} {
+		// The request context path may start with the stage or a base path mapping
+		for i := 0; i < len(candidate); i++ {
+			if candidate[i] != '/' || candidate[i:] == e.Path {
+				continue
+			}
+			if p, err := url.PathUnescape(candidate[i:]); err == nil && p == e.Path {
+				return candidate[i:]
+			}
+		}
+	}
+	return ""
+}
+
+// expandResource fills the {name} and {name+} placeholders of a resource
+// template with the path parameters, or returns "" if one is missing.
+func expandResource(resource string, params map[string]string) string {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(resource, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(resource[start:], '}')
+		if end < 0 {
+			break
+		}
+		v, ok := params[strings.TrimSuffix(resource[start+1:start+end], "+")]
+		if !ok {
+			return ""
+		}
+		b.WriteString(resource[:start])
+		b.WriteString(v)
+		resource = resource[start+end+1:]
+	}
+	b.WriteString(resource)
+	return b.String()
+}
+
+// queryOrder returns the query parameter names of a V1 payload in the order the
+// payload lists them, which API Gateway takes from the query string of the
+// request. The decoded event loses it, since the parameters are kept in maps.
+func queryOrder(payload []byte) []string {
+	var raw struct {
+		MultiValue json.RawMessage `json:"multiValueQueryStringParameters"`
+		Single     json.RawMessage `json:"queryStringParameters"`
+	}
+	if len(payload) == 0 || json.Unmarshal(payload, &raw) != nil {
+		return nil
+	}
+
+	params := raw.MultiValue
+	if len(params) == 0 || string(params) == "null" {
+		params = raw.Single
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(params))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return nil
+	}
+
+	var keys []string
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil
+		}
+		keys = append(keys, t.(string))
+	}
+	return keys
+}
+
+// rawQueryV1 rebuilds the query string of a V1 event, keys in the given order
+// followed by any others sorted, values of a key in the order they were sent.
+// API Gateway decodes the parameters, so they are escaped again the way RFC 3986
+// and most URL signatures do, with %20 for spaces. Repeated keys interleaved with
+// other keys cannot be restored.
+func rawQueryV1(e events.APIGatewayProxyRequest, order []string) string {
+	params := e.MultiValueQueryStringParameters
+	if len(params) == 0 {
+		params = make(map[string][]string, len(e.QueryStringParameters))
+		for k, v := range e.QueryStringParameters {
+			params[k] = []string{v}
+		}
+	}
+
+	keys := make([]string, 0, len(params))
+	seen := make(map[string]bool, len(params))
+	for _, k := range order {
+		if _, ok := params[k]; ok && !seen[k] {
+			keys = append(keys, k)
+			seen[k] = true
+		}
+	}
+	rest := len(keys)
+	for k := range params {
+		if !seen[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys[rest:])
+
+	var parts []string
+	for _, k := range keys {
+		for _, v := range params[k] {
+			parts = append(parts, escapeQuery(k)+"="+escapeQuery(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// escapeQuery escapes a query component, with %20 instead of + for spaces.
+func escapeQuery(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
@@ -0,0 +1,148 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestAutoGateway_URLFidelity(t *testing.T) {
+	// A hand-written event in the shape of a REST API proxy event, listing its
+	// query parameters in the order of the request URL
+	payload, err := os.ReadFile("testdata/apigw-v1-escaped-request.json")
+	if err != nil {
+		t.Fatalf("failed to read event: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		opts        []Option
+		escapedPath string
+		rawQuery    string
+	}{
+		{
+			name:        "fidelity",
+			opts:        []Option{func(o *Options) { o.URLFidelity = true }},
+			escapedPath: "/files/reports%2F2024%20q1.pdf",
+			rawQuery:    "token=abc%2Fdef%2Bghi%3D&expires=1710342820&tag=a&tag=b&name=2024%20q1",
+		},
+		{
+			name:        "default",
+			escapedPath: "/files/reports/2024%20q1.pdf",
+			rawQuery:    "expires=1710342820&name=2024+q1&tag=a&tag=b&token=abc%2Fdef%2Bghi%3D",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *http.Request
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r
+			})
+
+			if _, err := NewAutoGateway(handler, tt.opts...).Invoke(context.Background(), payload); err != nil {
+				t.Fatalf("Invoke failed: %v", err)
+			}
+
+			if got.URL.Path != "/files/reports/2024 q1.pdf" {
+				t.Errorf("expected the decoded path, got %q", got.URL.Path)
+			}
+
+			if got.URL.EscapedPath() != tt.escapedPath {
+				t.Errorf("expected escaped path %q, got %q", tt.escapedPath, got.URL.EscapedPath())
+			}
+
+			if got.URL.RawQuery != tt.rawQuery {
+				t.Errorf("expected raw query %q, got %q", tt.rawQuery, got.URL.RawQuery)
+			}
+
+			if got.RequestURI != tt.escapedPath+"?"+tt.rawQuery {
+				t.Errorf("expected request URI %q, got %q", tt.escapedPath+"?"+tt.rawQuery, got.RequestURI)
+			}
+
+			if got.PathValue("key") != "reports/2024 q1.pdf" {
+				t.Errorf("expected path value key %q, got %q", "reports/2024 q1.pdf", got.PathValue("key"))
+			}
+
+			if got.URL.Query().Get("token") != "abc/def+ghi=" {
+				t.Errorf("expected token abc/def+ghi=, got %q", got.URL.Query().Get("token"))
+			}
+		})
+	}
+}
+
+func TestRawPathV1(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    events.APIGatewayProxyRequest
+		expected string
+	}{
+		{
+			name: "custom domain base path",
+			event: events.APIGatewayProxyRequest{
+				Path:           "/a/b",
+				RequestContext: events.APIGatewayProxyRequestContext{Path: "/v1/a%2Fb"},
+			},
+			expected: "/a%2Fb",
+		},
+		{
+			name: "greedy path parameter",
+			event: events.APIGatewayProxyRequest{
+				Path:           "/files/a/b",
+				Resource:       "/files/{proxy+}",
+				PathParameters: map[string]string{"proxy": "a%2Fb"},
+			},
+			expected: "/files/a%2Fb",
+		},
+		{
+			name: "nothing escaped",
+			event: events.APIGatewayProxyRequest{
+				Path:           "/a/b",
+				RequestContext: events.APIGatewayProxyRequestContext{Path: "/prod/a/b"},
+			},
+			expected: "",
+		},
+		{
+			name: "decoded elsewhere",
+			event: events.APIGatewayProxyRequest{
+				Path:           "/a/b",
+				Resource:       "/{proxy+}",
+				PathParameters: map[string]string{"proxy": "a/c"},
+				RequestContext: events.APIGatewayProxyRequestContext{Path: "/prod/a%2Fc"},
+			},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rawPathV1(tt.event); got != tt.expected {
+				t.Errorf("expected raw path %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestQueryOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		expected []string
+	}{
+		{"multi-value", `{"queryStringParameters":{"b":"1","a":"2"},"multiValueQueryStringParameters":{"z":["1"],"b":["2"],"a":["3"]}}`, []string{"z", "b", "a"}},
+		{"single-value", `{"queryStringParameters":{"z":"1","a":"2"},"multiValueQueryStringParameters":null}`, []string{"z", "a"}},
+		{"no query", `{"queryStringParameters":null}`, nil},
+		{"no payload", ``, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := queryOrder([]byte(tt.payload)); !equalStringSlices(got, tt.expected) {
+				t.Errorf("expected keys %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
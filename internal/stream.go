@@ -0,0 +1,204 @@
+package internal
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+// ===========================
+// Streaming Gateway
+// ===========================
+
+// StreamingGateway wraps an http.Handler for Function URLs configured with
+// InvokeMode RESPONSE_STREAM. The response is handed to the Lambda runtime as
+// soon as the handler commits its headers and the body is streamed as it is written.
+type StreamingGateway[T any] struct {
+	handler          http.Handler
+	requestConverter RequestConverter[T]
+}
+
+// NewStreamingGateway creates a new StreamingGateway with the given handler and request converter
+func NewStreamingGateway[T any](handler http.Handler, requestConverter RequestConverter[T]) *StreamingGateway[T] {
+	return &StreamingGateway[T]{handler: handler, requestConverter: requestConverter}
+}
+
+// InvokeStream handles the Lambda invocation by converting the event to an HTTP request and
+// serving it in the background. It returns once the handler has committed its status and headers;
+// the returned response reads the status/header prelude followed by the body as it is written.
+func (gw *StreamingGateway[T]) InvokeStream(ctx context.Context, payload json.RawMessage) (*events.LambdaFunctionURLStreamingResponse, error) {
+	var evt T
+
+	// Unmarshal the payload into the generic event type T
+	if err := json.Unmarshal(payload, &evt); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnmarshal, err)
+	}
+
+	// Convert the event to an *http.Request using the converter function
+	req, err := gw.requestConverter(ctx, evt)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRequestConversion, err)
+	}
+
+	pr, pw := io.Pipe()
+	w := NewStreamingResponse(pw)
+
+	// Serve the HTTP request in the background, the body is read while it is written
+	go func() {
+		if err := serveHTTP(gw.handler, w, req); err != nil {
+			// A panic is logged like DefaultErrorHandler does and cuts the body short
+			logError(ctx, err)
+			if !w.wroteHeader {
+				w.statusCode = http.StatusInternalServerError
+			}
+			w.commit(nil)
+			pw.CloseWithError(err)
+			return
+		}
+		w.commit(nil)
+		pw.Close()
+	}()
+
+	// Wait until the status and headers are known
+	select {
+	case <-w.committed:
+	case <-ctx.Done():
+		pr.CloseWithError(ctx.Err())
+		return nil, ctx.Err()
+	}
+
+	headers, cookies, err := foldHeaders(req.Context(), w.sent)
+	if err != nil {
+		pr.CloseWithError(err)
+		return nil, fmt.Errorf("%w: %w", ErrResponseConversion, err)
+	}
+
+	return &events.LambdaFunctionURLStreamingResponse{
+		StatusCode: w.statusCode,
+		Headers:    headers,
+		Cookies:    cookies,
+		Body:       pr,
+	}, nil
+}
+
+// Invoke serves the invocation like InvokeStream but reads the whole body into a
+// buffered Function URL response, so the Emulator can serve the gateway.
+func (gw *StreamingGateway[T]) Invoke(ctx context.Context, payload []byte) ([]byte, error) {
+	resp, err := gw.InvokeStream(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(events.LambdaFunctionURLResponse{
+		StatusCode:      resp.StatusCode,
+		Headers:         resp.Headers,
+		Cookies:         resp.Cookies,
+		Body:            base64.StdEncoding.EncodeToString(body),
+		IsBase64Encoded: true,
+	})
+}
+
+// ListenAndServeStream sets up a StreamingGateway and starts the Lambda handler.
+// Outside of Lambda it serves the handler on addr with net/http instead, or through
+// the Emulator when GATEWAY_EMULATOR is set.
+// Response streaming requires the provided.al2 or provided.al2023 runtime, or building with -tags lambda.norpc.
+func ListenAndServeStream[T any](addr string, handler http.Handler, requestConverter RequestConverter[T]) error {
+	if handler == nil {
+		handler = http.DefaultServeMux
+	}
+
+	gw := NewStreamingGateway[T](handler, requestConverter)
+
+	if !IsLambda() {
+		return serveLocal(addr, handler, gw, FormatOf[T]())
+	}
+
+	lambda.Start(gw.InvokeStream)
+
+	return nil
+}
+
+// ===========================
+// Streaming ResponseWriter
+// ===========================
+
+// StreamingResponseWriter implements http.ResponseWriter and http.Flusher, passing
+// the body straight through to the Lambda runtime instead of buffering it.
+type StreamingResponseWriter struct {
+	w           io.Writer
+	header      http.Header
+	sent        http.Header
+	wroteHeader bool
+	statusCode  int
+	committed   chan struct{}
+	once        sync.Once
+}
+
+// NewStreamingResponse creates a new StreamingResponseWriter that writes the body to w.
+func NewStreamingResponse(w io.Writer) *StreamingResponseWriter {
+	return &StreamingResponseWriter{
+		w:          w,
+		header:     make(http.Header),
+		statusCode: http.StatusOK,
+		committed:  make(chan struct{}),
+	}
+}
+
+// Header returns the header map that will be sent by WriteHeader.
+func (w *StreamingResponseWriter) Header() http.Header {
+	return w.header
+}
+
+// Write streams the data to the client.
+func (w *StreamingResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.commit(b)
+	return w.w.Write(b)
+}
+
+// WriteHeader records the status code and headers. Changes to the header map made
+// after this call are not sent. Like net/http the headers reach the runtime with
+// the first Write or Flush, so the Content-Type can be sniffed from the body.
+func (w *StreamingResponseWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.statusCode = statusCode
+	w.wroteHeader = true
+	w.sent = w.header.Clone()
+}
+
+// Flush commits the headers if they have not been sent yet. The body itself is
+// never buffered, so every Write already reaches the runtime.
+func (w *StreamingResponseWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.commit(nil)
+}
+
+// commit snapshots the headers, sniffing the Content-Type from the first bytes of
+// the body, and releases InvokeStream. It is safe to call more than once.
+func (w *StreamingResponseWriter) commit(body []byte) {
+	w.once.Do(func() {
+		if w.sent == nil {
+			w.sent = w.header.Clone()
+		}
+		sniffContentType(w.sent, w.statusCode, body)
+		close(w.committed)
+	})
+}
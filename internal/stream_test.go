@@ -0,0 +1,289 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// fakeStreamingRuntime is a local stand-in for the Lambda Runtime API response endpoint.
+// Like the real service it reads the prelude up to the 8 byte NUL delimiter and then
+// relays each body chunk as soon as it arrives.
+type fakeStreamingRuntime struct {
+	server      *httptest.Server
+	contentType string
+	prelude     chan map[string]any
+	chunks      chan string
+}
+
+func newFakeStreamingRuntime(t *testing.T) *fakeStreamingRuntime {
+	rt := &fakeStreamingRuntime{
+		prelude: make(chan map[string]any, 1),
+		chunks:  make(chan string, 16),
+	}
+	rt.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/response") {
+			http.NotFound(w, r)
+			return
+		}
+		rt.contentType = r.Header.Get("Content-Type")
+		defer close(rt.chunks)
+
+		var pending []byte
+		delimiter := make([]byte, 8)
+		buf := make([]byte, 1024)
+		preludeDone := false
+		for {
+			n, err := r.Body.Read(buf)
+			data := buf[:n]
+			if !preludeDone && n > 0 {
+				pending = append(pending, data...)
+				i := bytes.Index(pending, delimiter)
+				if i < 0 {
+					continue
+				}
+				var p map[string]any
+				if err := json.Unmarshal(pending[:i], &p); err != nil {
+					t.Errorf("invalid prelude %q: %v", pending[:i], err)
+				}
+				rt.prelude <- p
+				preludeDone = true
+				data = pending[i+len(delimiter):]
+			}
+			if preludeDone && len(data) > 0 {
+				rt.chunks <- string(data)
+			}
+			if err != nil {
+				break
+			}
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(rt.server.Close)
+	return rt
+}
+
+// post sends the streaming response the way the aws-lambda-go runtime client does.
+func (rt *fakeStreamingRuntime) post(t *testing.T, resp *events.LambdaFunctionURLStreamingResponse) {
+	url := rt.server.URL + "/2018-06-01/runtime/invocation/test-request-id/response"
+	req, err := http.NewRequest(http.MethodPost, url, io.Reader(resp))
+	if err != nil {
+		t.Errorf("failed to create request: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", resp.ContentType())
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Errorf("failed to post response: %v", err)
+		return
+	}
+	res.Body.Close()
+}
+
+func (rt *fakeStreamingRuntime) nextChunk(t *testing.T) string {
+	select {
+	case c := <-rt.chunks:
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for a streamed chunk")
+		return ""
+	}
+}
+
+func TestStreamingGateway_InvokeStream(t *testing.T) {
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Add("Set-Cookie", "session=abc")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("data: first\n\n"))
+		w.(http.Flusher).Flush()
+		<-release
+		w.Write([]byte("data: second\n\n"))
+	})
+
+	gw := NewStreamingGateway(handler, ConvertLambdaFunctionURLRequest)
+
+	event := events.LambdaFunctionURLRequest{
+		Version: "2.0",
+		RawPath: "/events",
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{
+				Method: "GET",
+			},
+		},
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("failed to marshal event: %v", err)
+	}
+
+	resp, err := gw.InvokeStream(context.Background(), payload)
+	if err != nil {
+		t.Fatalf("InvokeStream failed: %v", err)
+	}
+
+	rt := newFakeStreamingRuntime(t)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rt.post(t, resp)
+	}()
+
+	prelude := <-rt.prelude
+	if prelude["statusCode"] != float64(http.StatusAccepted) {
+		t.Errorf("expected status code %d, got %v", http.StatusAccepted, prelude["statusCode"])
+	}
+	headers, _ := prelude["headers"].(map[string]any)
+	if headers["Content-Type"] != "text/event-stream" {
+		t.Errorf("expected Content-Type text/event-stream, got %v", headers["Content-Type"])
+	}
+	cookies, _ := prelude["cookies"].([]any)
+	if len(cookies) != 1 || cookies[0] != "session=abc" {
+		t.Errorf("expected cookies [session=abc], got %v", cookies)
+	}
+
+	// The first event must arrive while the handler is still blocked
+	if c := rt.nextChunk(t); c != "data: first\n\n" {
+		t.Errorf("expected first chunk %q, got %q", "data: first\n\n", c)
+	}
+
+	close(release)
+	if c := rt.nextChunk(t); c != "data: second\n\n" {
+		t.Errorf("expected second chunk %q, got %q", "data: second\n\n", c)
+	}
+	<-done
+
+	if rt.contentType != "application/vnd.awslambda.http-integration-response" {
+		t.Errorf("expected streaming content type, got %q", rt.contentType)
+	}
+}
+
+func TestStreamingGateway_InvokeStream_EmptyResponse(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Custom-Header", "value")
+	})
+
+	gw := NewStreamingGateway(handler, ConvertLambdaFunctionURLRequest)
+
+	resp, err := gw.InvokeStream(context.Background(), []byte(`{"version":"2.0","rawPath":"/","requestContext":{"http":{"method":"GET"}}}`))
+	if err != nil {
+		t.Fatalf("InvokeStream failed: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	if resp.Headers["X-Custom-Header"] != "value" {
+		t.Errorf("expected X-Custom-Header value, got %q", resp.Headers["X-Custom-Header"])
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read body: %v", err)
+	}
+	if len(body) != 0 {
+		t.Errorf("expected empty body, got %q", body)
+	}
+}
+
+func TestStreamingGateway_InvokeStream_Panic(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	gw := NewStreamingGateway(handler, ConvertLambdaFunctionURLRequest)
+
+	resp, err := gw.InvokeStream(context.Background(), []byte(`{"version":"2.0","rawPath":"/","requestContext":{"http":{"method":"GET"}}}`))
+	if err != nil {
+		t.Fatalf("InvokeStream failed: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected status code %d, got %d", http.StatusInternalServerError, resp.StatusCode)
+	}
+
+	var perr *PanicError
+	if _, err := io.ReadAll(resp.Body); !errors.As(err, &perr) || perr.Value != "boom" {
+		t.Errorf("expected the body to fail with the panic, got %v", err)
+	}
+}
+
+func TestStreamingResponseWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewStreamingResponse(&buf)
+
+	w.Header().Set("X-Before", "1")
+	w.WriteHeader(http.StatusCreated)
+	w.Header().Set("X-After", "1")
+	w.Write([]byte("test body"))
+
+	if w.statusCode != http.StatusCreated {
+		t.Errorf("expected status code %d, got %d", http.StatusCreated, w.statusCode)
+	}
+
+	if w.sent.Get("X-Before") != "1" {
+		t.Errorf("expected X-Before to be sent")
+	}
+
+	if w.sent.Get("X-After") != "" {
+		t.Errorf("expected X-After to be set too late to be sent")
+	}
+
+	if buf.String() != "test body" {
+		t.Errorf("expected body %q, got %q", "test body", buf.String())
+	}
+}
+
+func TestStreamingResponseWriter_Sniff(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewStreamingResponse(&buf)
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("<!DOCTYPE html><html></html>"))
+	w.Write([]byte{0x89, 0x50, 0x4E, 0x47})
+
+	if ct := w.sent.Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("expected the Content-Type sniffed from the first write, got %q", ct)
+	}
+}
+
+func TestStreamingGateway_Emulator(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: 1\n\n"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("data: 2\n\n"))
+	})
+
+	gw := NewStreamingGateway[events.LambdaFunctionURLRequest](handler, ConvertLambdaFunctionURLRequest)
+	srv := httptest.NewServer(NewEmulator(gw, FormatFunctionURL))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/events")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("expected Content-Type text/event-stream, got %q", resp.Header.Get("Content-Type"))
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "data: 1\n\ndata: 2\n\n" {
+		t.Errorf("expected both events, got %q", string(body))
+	}
+}
@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ===========================
+// Connection State
+// ===========================
+
+// setConnection fills in what the connection of a converted request would have
+// told net/http: the URL scheme, taken from X-Forwarded-Proto or else scheme, the
+// protocol version of the event and, for https, a TLS state carrying the mutual
+// TLS client certificate, if any. The TLS version and cipher suite are not part
+// of the events and stay zero.
+func setConnection(req *http.Request, scheme, proto, clientCertPEM string) {
+	if xfp, _, _ := strings.Cut(req.Header.Get("X-Forwarded-Proto"), ","); xfp != "" {
+		if xfp = strings.ToLower(strings.TrimSpace(xfp)); xfp == "http" || xfp == "https" {
+			scheme = xfp
+		}
+	}
+	req.URL.Scheme = scheme
+
+	if major, minor, ok := parseProto(proto); ok {
+		req.Proto = fmt.Sprintf("HTTP/%d.%d", major, minor)
+		req.ProtoMajor, req.ProtoMinor = major, minor
+	}
+
+	if scheme != "https" {
+		return
+	}
+
+	state := &tls.ConnectionState{
+		HandshakeComplete: true,
+		ServerName:        req.Host,
+		PeerCertificates:  parseCertificates(clientCertPEM),
+	}
+	if host, _, err := net.SplitHostPort(req.Host); err == nil {
+		state.ServerName = host
+	}
+	if req.ProtoMajor == 2 {
+		state.NegotiatedProtocol = "h2"
+	}
+	req.TLS = state
+}
+
+// parseProto parses an HTTP version such as HTTP/1.1, or HTTP/2 without a minor version.
+func parseProto(proto string) (int, int, bool) {
+	if major, minor, ok := http.ParseHTTPVersion(proto); ok {
+		return major, minor, true
+	}
+	return http.ParseHTTPVersion(proto + ".0")
+}
+
+// parseCertificates decodes the certificates of a PEM bundle, skipping any
+// that do not parse.
+func parseCertificates(data string) []*x509.Certificate {
+	var certs []*x509.Certificate
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			return certs
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+			certs = append(certs, cert)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package internal
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// testClientCert returns a self-signed client certificate in PEM form.
+func testClientCert(t *testing.T) string {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestConvertRequest_Connection(t *testing.T) {
+	certPEM := testClientCert(t)
+
+	v1 := events.APIGatewayProxyRequest{
+		HTTPMethod: "GET",
+		Path:       "/",
+		Headers:    map[string]string{"Host": "api.example.com"},
+		RequestContext: events.APIGatewayProxyRequestContext{
+			Protocol: "HTTP/1.1",
+		},
+	}
+	v1Payload, err := json.Marshal(v1)
+	if err != nil {
+		t.Fatalf("failed to marshal event: %v", err)
+	}
+	// events.APIGatewayRequestIdentity has no clientCert, add it to the JSON
+	var raw map[string]any
+	json.Unmarshal(v1Payload, &raw)
+	raw["requestContext"].(map[string]any)["identity"] = map[string]any{
+		"sourceIp":   "203.0.113.1",
+		"clientCert": map[string]any{"clientCertPem": certPEM},
+	}
+	v1Payload, _ = json.Marshal(raw)
+
+	tests := []struct {
+		name       string
+		payload    any
+		scheme     string
+		protoMajor int
+		cert       bool
+	}{
+		{
+			name:       "api gateway v1 mutual tls",
+			payload:    json.RawMessage(v1Payload),
+			scheme:     "https",
+			protoMajor: 1,
+			cert:       true,
+		},
+		{
+			name: "api gateway v2 mutual tls",
+			payload: events.APIGatewayV2HTTPRequest{
+				Version: "2.0",
+				RawPath: "/",
+				Headers: map[string]string{"host": "api.example.com"},
+				RequestContext: events.APIGatewayV2HTTPRequestContext{
+					HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET", Protocol: "HTTP/2"},
+					Authentication: events.APIGatewayV2HTTPRequestContextAuthentication{
+						ClientCert: events.APIGatewayV2HTTPRequestContextAuthenticationClientCert{ClientCertPem: certPEM},
+					},
+				},
+			},
+			scheme:     "https",
+			protoMajor: 2,
+			cert:       true,
+		},
+		{
+			name: "function url",
+			payload: events.LambdaFunctionURLRequest{
+				Version: "2.0",
+				RawPath: "/",
+				RequestContext: events.LambdaFunctionURLRequestContext{
+					DomainName: "abc.lambda-url.us-east-1.on.aws",
+					HTTP:       events.LambdaFunctionURLRequestContextHTTPDescription{Method: "GET", Protocol: "HTTP/1.1"},
+				},
+			},
+			scheme:     "https",
+			protoMajor: 1,
+		},
+		{
+			name: "alb http listener",
+			payload: events.ALBTargetGroupRequest{
+				HTTPMethod:     "GET",
+				Path:           "/",
+				Headers:        map[string]string{"host": "alb.example.com", "x-forwarded-proto": "http"},
+				RequestContext: events.ALBTargetGroupRequestContext{ELB: events.ELBContext{TargetGroupArn: "arn"}},
+			},
+			scheme:     "http",
+			protoMajor: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := json.Marshal(tt.payload)
+			if err != nil {
+				t.Fatalf("failed to marshal event: %v", err)
+			}
+
+			var got *http.Request
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r
+			})
+			if _, err := NewAutoGateway(handler).Invoke(context.Background(), payload); err != nil {
+				t.Fatalf("Invoke failed: %v", err)
+			}
+
+			if got.URL.Scheme != tt.scheme {
+				t.Errorf("expected scheme %s, got %q", tt.scheme, got.URL.Scheme)
+			}
+
+			if got.ProtoMajor != tt.protoMajor || !got.ProtoAtLeast(tt.protoMajor, 0) {
+				t.Errorf("expected protocol major version %d, got %s", tt.protoMajor, got.Proto)
+			}
+
+			if (got.TLS != nil) != (tt.scheme == "https") {
+				t.Fatalf("expected TLS state only for https, got %v", got.TLS)
+			}
+
+			if got.TLS == nil {
+				return
+			}
+
+			if !got.TLS.HandshakeComplete || got.TLS.ServerName != got.Host {
+				t.Errorf("expected a completed handshake for %s, got %+v", got.Host, got.TLS)
+			}
+
+			if tt.cert {
+				if len(got.TLS.PeerCertificates) != 1 || got.TLS.PeerCertificates[0].Subject.CommonName != "client" {
+					t.Errorf("expected the client certificate, got %v", got.TLS.PeerCertificates)
+				}
+			} else if len(got.TLS.PeerCertificates) != 0 {
+				t.Errorf("expected no client certificate, got %v", got.TLS.PeerCertificates)
+			}
+		})
+	}
+}
+
+func TestParseProto(t *testing.T) {
+	tests := []struct {
+		proto        string
+		major, minor int
+		ok           bool
+	}{
+		{"HTTP/1.1", 1, 1, true},
+		{"HTTP/1.0", 1, 0, true},
+		{"HTTP/2", 2, 0, true},
+		{"HTTP/2.0", 2, 0, true},
+		{"", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		major, minor, ok := parseProto(tt.proto)
+		if major != tt.major || minor != tt.minor || ok != tt.ok {
+			t.Errorf("expected %q to parse as %d.%d (%v), got %d.%d (%v)", tt.proto, tt.major, tt.minor, tt.ok, major, minor, ok)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package gateway
+
+import "github.com/go-obvious/gateway/internal"
+
+// DefaultMaxResponseSize is the 6 MB limit Lambda puts on the response payload
+// of a synchronous invocation.
+const DefaultMaxResponseSize = internal.DefaultMaxResponseSize
+
+// OversizePolicy replaces a response whose encoded payload of size bytes is over
+// the size budget. Returning an error passes it to the ErrorHandler.
+type OversizePolicy = internal.OversizePolicy
+
+// RejectOversized replaces the response with an application/problem+json
+// response with the given status, such as 413 or 502.
+func RejectOversized(status int) OversizePolicy {
+	return internal.RejectOversized(status)
+}
+
+// CompressOversized gzips the body when the client accepts it and leaves the
+// response to fallback when it cannot be compressed or is still too large.
+func CompressOversized(fallback OversizePolicy) OversizePolicy {
+	return internal.CompressOversized(fallback)
+}
@@ -0,0 +1,155 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-obvious/gateway/internal"
+)
+
+// RequestConverter converts an event of type T to an *http.Request.
+type RequestConverter[T any] func(context.Context, T) (*http.Request, error)
+
+// ResponseConverter converts the response of the handler to a response of type R.
+type ResponseConverter[R any] func(ResponseData) (R, error)
+
+// ResponseData is the response captured from the handler.
+type ResponseData = internal.ResponseData
+
+// ErrorHandler decides the response for an invocation whose event or response
+// failed to convert, whose handler panicked with a *PanicError, ran into the
+// deadline with ErrHandlerTimeout or answered with more than the response limit
+// with ErrResponseTooLarge. Returning an error fails the invocation instead.
+type ErrorHandler func(ctx context.Context, err error) (ResponseData, error)
+
+// DefaultDeadlineMargin is how long before the Lambda deadline a Gateway gives up
+// on the handler unless WithDeadlineMargin says otherwise.
+const DefaultDeadlineMargin = internal.DefaultDeadlineMargin
+
+// Option configures a Gateway.
+type Option func(*internal.Options)
+
+// WithRequestConverter converts every event as a T with c instead of detecting its format.
+func WithRequestConverter[T any](c RequestConverter[T]) Option {
+	return func(o *internal.Options) {
+		o.Format = internal.FormatOf[T]()
+		o.DecodeRequest = internal.NewRequestDecoder(internal.RequestConverter[T](c))
+	}
+}
+
+// WithResponseConverter converts every response to an R with c instead of
+// answering in the format of the event.
+func WithResponseConverter[R any](c ResponseConverter[R]) Option {
+	return func(o *internal.Options) {
+		o.EncodeResponse = internal.NewResponseEncoder(internal.ResponseConverter[R](c))
+	}
+}
+
+// WithErrorHandler answers conversion errors, handler panics, timeouts and
+// oversized responses with the response returned by h instead of
+// DefaultErrorHandler. Use errors.Is with ErrUnmarshal, ErrRequestConversion,
+// ErrResponseConversion, ErrHandlerTimeout and ErrResponseTooLarge to tell the
+// failures apart, and errors.As with *PanicError to spot a panic. To fail the
+// invocation instead, return the error.
+func WithErrorHandler(h ErrorHandler) Option {
+	return func(o *internal.Options) {
+		o.ErrorHandler = internal.ErrorHandler(h)
+	}
+}
+
+// WithBasePath strips the prefix chosen by policy from the request path before
+// the handler sees it. See StripStage, StripPrefix and DetectBasePath.
+func WithBasePath(policy BasePathPolicy) Option {
+	return func(o *internal.Options) {
+		o.BasePath = policy
+	}
+}
+
+// WithInjectedHeaders copies request context fields into request headers as
+// policy asks, instead of setting X-Request-Id and X-Stage.
+func WithInjectedHeaders(policy HeaderPolicy) Option {
+	return func(o *internal.Options) {
+		o.Headers = &policy
+	}
+}
+
+// WithoutInjectedHeaders leaves the request headers exactly as the client sent them.
+func WithoutInjectedHeaders() Option {
+	return WithInjectedHeaders(HeaderPolicy{})
+}
+
+// WithDeadlineMargin cancels the request context d before the Lambda deadline and
+// answers the invocation with ErrHandlerTimeout, a 504 by default, if the handler
+// has not finished by then. A negative d waits for the handler.
+func WithDeadlineMargin(d time.Duration) Option {
+	return func(o *internal.Options) {
+		o.DeadlineMargin = d
+	}
+}
+
+// WithMaxResponseSize sets the budget for the encoded response payload, including
+// base64 and headers, instead of DefaultMaxResponseSize. A negative n turns the check off.
+func WithMaxResponseSize(n int) Option {
+	return func(o *internal.Options) {
+		o.MaxResponseSize = n
+	}
+}
+
+// WithOversizePolicy replaces responses over the size budget with the one
+// returned by policy. See RejectOversized and CompressOversized.
+func WithOversizePolicy(policy OversizePolicy) Option {
+	return func(o *internal.Options) {
+		o.OversizePolicy = policy
+	}
+}
+
+// WithCompression compresses response bodies with gzip, deflate or, when
+// c.Brotli is set, brotli, whichever the client prefers. Compressed bodies are
+// sent base64 encoded with Content-Encoding and Vary set.
+func WithCompression(c Compression) Option {
+	return func(o *internal.Options) {
+		o.Compression = &c
+	}
+}
+
+// WithBinaryPolicy decides which response bodies are base64 encoded with policy.
+// See BinaryMediaTypes.
+func WithBinaryPolicy(policy BinaryPolicy) Option {
+	return func(o *internal.Options) {
+		o.Binary = policy
+	}
+}
+
+// WithListHeaders splits the comma-joined values API Gateway V2, Function URLs and
+// ALBs deliver for the named request headers, on top of the RFC 9110 list headers.
+func WithListHeaders(names ...string) Option {
+	return func(o *internal.Options) {
+		o.HeaderFolding.List = append(o.HeaderFolding.List, names...)
+	}
+}
+
+// WithSingletonHeaders keeps the values of the named request headers whole, even
+// if they contain commas.
+func WithSingletonHeaders(names ...string) Option {
+	return func(o *internal.Options) {
+		o.HeaderFolding.Singleton = append(o.HeaderFolding.Singleton, names...)
+	}
+}
+
+// WithStrictHeaderFolding fails API Gateway V2 and Function URL responses that
+// repeat a header which is not a list, instead of sending its first value.
+func WithStrictHeaderFolding() Option {
+	return func(o *internal.Options) {
+		o.HeaderFolding.Strict = true
+	}
+}
+
+// WithURLFidelity rebuilds the URL of API Gateway V1 events as sent, as far as
+// the event allows: escaped path segments such as %2F are kept in URL.RawPath,
+// and the query string keeps the order of its parameters, for signed URLs.
+func WithURLFidelity() Option {
+	return func(o *internal.Options) {
+		o.URLFidelity = true
+	}
+}
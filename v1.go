@@ -1,6 +1,7 @@
 package gateway
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -9,10 +10,14 @@ import (
 )
 
 func ListenAndServeV1(addr string, h http.Handler) error {
-	return internal.ListenAndServe[events.APIGatewayProxyRequest, events.APIGatewayProxyResponse](
-		"",
-		h,
-		internal.ConvertAPIGatewayProxyRequest,
-		internal.ConvertResponseV1,
-	)
+	return New(h,
+		WithRequestConverter(internal.ConvertAPIGatewayProxyRequest),
+		WithResponseConverter(internal.ConvertResponseV1),
+	).ListenAndServe(addr)
+}
+
+// V1Event returns the API Gateway V1 event that produced the request, if it was
+// served from one. Its RequestContext holds the authorizer, stage and API ID.
+func V1Event(ctx context.Context) (events.APIGatewayProxyRequest, bool) {
+	return internal.RequestContext[events.APIGatewayProxyRequest](ctx)
 }
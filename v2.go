@@ -1,6 +1,7 @@
 package gateway
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -9,10 +10,14 @@ import (
 )
 
 func ListenAndServeV2(addr string, h http.Handler) error {
-	return internal.ListenAndServe[events.APIGatewayV2HTTPRequest, events.APIGatewayV2HTTPResponse](
-		"",
-		h,
-		internal.ConvertAPIGatewayV2HTTPRequest,
-		internal.ConvertResponseV2,
-	)
+	return New(h,
+		WithRequestConverter(internal.ConvertAPIGatewayV2HTTPRequest),
+		WithResponseConverter(internal.ConvertResponseV2),
+	).ListenAndServe(addr)
+}
+
+// V2Event returns the API Gateway V2 event that produced the request, if it was
+// served from one. Its RequestContext holds the authorizer, stage and API ID.
+func V2Event(ctx context.Context) (events.APIGatewayV2HTTPRequest, bool) {
+	return internal.RequestContext[events.APIGatewayV2HTTPRequest](ctx)
 }